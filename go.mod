@@ -5,6 +5,7 @@ go 1.24.0
 require (
 	github.com/lwmacct/250300-go-mod-mlog v0.0.1
 	github.com/lwmacct/250300-go-mod-pkgs v0.0.6
+	golang.org/x/sync v0.18.0
 )
 
 require gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
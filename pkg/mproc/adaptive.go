@@ -0,0 +1,42 @@
+package mproc
+
+import (
+	"math"
+	"time"
+)
+
+// 变化检测的阈值: 速率相对变化不超过 adaptiveStable 视为平稳, 超过 adaptiveBurst 视为突变
+const (
+	adaptiveStable = 0.1
+	adaptiveBurst  = 0.5
+)
+
+// adaptiveInterval 根据速率变化调整采样间隔: 平稳时逐步加倍, 突变时回到最小间隔
+type adaptiveInterval struct {
+	min, max time.Duration
+	current  time.Duration
+	lastRate float64
+	hasRate  bool
+}
+
+// newAdaptiveInterval 创建自适应间隔, 最小间隔不低于 MinInterval, 最大间隔不低于最小间隔
+func newAdaptiveInterval(minInterval, maxInterval time.Duration) *adaptiveInterval {
+	minInterval = max(minInterval, MinInterval)
+	maxInterval = max(maxInterval, minInterval)
+	return &adaptiveInterval{min: minInterval, max: maxInterval, current: minInterval}
+}
+
+// next 根据本次速率返回下一次采样的间隔
+func (a *adaptiveInterval) next(rate float64) time.Duration {
+	if a.hasRate {
+		change := math.Abs(rate-a.lastRate) / math.Max(a.lastRate, 1)
+		switch {
+		case change <= adaptiveStable:
+			a.current = min(a.current*2, a.max)
+		case change >= adaptiveBurst:
+			a.current = a.min
+		}
+	}
+	a.lastRate, a.hasRate = rate, true
+	return a.current
+}
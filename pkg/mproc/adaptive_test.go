@@ -0,0 +1,77 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveInterval(t *testing.T) {
+	a := newAdaptiveInterval(time.Second, 8*time.Second)
+
+	// 平稳时逐步加倍, 不超过 max
+	var got []time.Duration
+	for range 5 {
+		got = append(got, a.next(1000))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stable intervals = %v, want %v", got, want)
+		}
+	}
+
+	// 突变时回到 min
+	if got := a.next(5000); got != time.Second {
+		t.Fatalf("after burst: interval = %v, want 1s", got)
+	}
+	// 中等变化保持当前间隔
+	a.next(5000)
+	if got := a.next(6500); got != 2*time.Second {
+		t.Fatalf("after moderate change: interval = %v, want 2s", got)
+	}
+}
+
+// 空闲时 (速率为 0) 视为平稳
+func TestAdaptiveIntervalIdle(t *testing.T) {
+	a := newAdaptiveInterval(time.Second, time.Minute)
+	a.next(0)
+	if got := a.next(0); got != 2*time.Second {
+		t.Fatalf("idle: interval = %v, want 2s", got)
+	}
+}
+
+func TestAdaptiveIntervalClamp(t *testing.T) {
+	a := newAdaptiveInterval(0, -time.Second)
+	if a.min != MinInterval || a.max != MinInterval {
+		t.Fatalf("min/max = %v/%v, want both %v", a.min, a.max, MinInterval)
+	}
+}
+
+func TestWithAdaptiveInterval(t *testing.T) {
+	// 间隔变化后按实际经过的时间计算速率; 注入的时钟按预期的间隔前进, 使结果确定
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{0, 100, 200, 400, 800, 1200, 1300}
+	var reads int
+	clock := WithClock(func() time.Time {
+		at := base.Add(offsets[min(reads, len(offsets)-1)] * time.Millisecond)
+		reads++
+		return at
+	})
+	n := newFIFONetDev(t, time.Second, WithAdaptiveInterval(100*time.Millisecond, 400*time.Millisecond), clock)
+	n.feed(netDevFile("eth0", 0, 0))
+	// 第一个采样只记录速率, 之后每个平稳的采样使间隔加倍
+	for i, want := range []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		n.feed(netDevFile("eth0", 0, 0))
+		if data := n.next(); data.Interval != want {
+			t.Fatalf("sample %d: Interval = %v, want %v", i, data.Interval, want)
+		}
+	}
+	n.feed(netDevFile("eth0", 1<<20, 0))
+	if data := n.next(); data.Interval != 400*time.Millisecond || data.BytesRx != 1<<20*10/4 {
+		t.Fatalf("burst: Interval = %v, BytesRx = %d; want 400ms, %d", data.Interval, data.BytesRx, 1<<20*10/4)
+	}
+	n.feed(netDevFile("eth0", 1<<20, 0))
+	if data := n.next(); data.Interval != 100*time.Millisecond {
+		t.Fatalf("after burst: Interval = %v, want 100ms", data.Interval)
+	}
+}
@@ -0,0 +1,84 @@
+package mproc
+
+import "math"
+
+const (
+	anomalyWindow     = 60  // 计算均值和标准差的采样数
+	anomalyMinSamples = 10  // 采样数不足时不做检测
+	anomalySigma      = 3.0 // 默认阈值
+)
+
+// rollingStats 最近若干个值的均值和标准差
+type rollingStats struct {
+	values []float64
+	next   int
+}
+
+func (r *rollingStats) add(v float64) {
+	if len(r.values) < anomalyWindow {
+		r.values = append(r.values, v)
+		return
+	}
+	r.values[r.next] = v
+	r.next = (r.next + 1) % anomalyWindow
+}
+
+// zScore 返回 v 相对于当前窗口的 z-score, 样本不足或标准差为 0 时返回 false
+func (r *rollingStats) zScore(v float64) (float64, bool) {
+	if len(r.values) < anomalyMinSamples {
+		return 0, false
+	}
+	var sum float64
+	for _, x := range r.values {
+		sum += x
+	}
+	mean := sum / float64(len(r.values))
+	var sq float64
+	for _, x := range r.values {
+		sq += (x - mean) * (x - mean)
+	}
+	std := math.Sqrt(sq / float64(len(r.values)))
+	if std == 0 {
+		return 0, false
+	}
+	return (v - mean) / std, true
+}
+
+// anomalyDetector 当任一检测指标的 z-score 绝对值超过 sigma 时回调
+type anomalyDetector struct {
+	sigma     float64
+	metrics   []Metric // 检测的速率指标, 为空时检测接收和发送字节速率
+	stats     map[Metric]*rollingStats
+	onAnomaly func(data TsCallData, z float64)
+}
+
+// check 检测本次采样, 回调的 z-score 取各指标中绝对值最大的一个;
+// 检测后再将本次速率计入窗口, 避免异常值稀释自己
+func (a *anomalyDetector) check(data TsCallData) {
+	metrics := a.metrics
+	if len(metrics) == 0 {
+		metrics = []Metric{MetricBytesRx, MetricBytesTx}
+	}
+	if a.stats == nil {
+		a.stats = make(map[Metric]*rollingStats)
+	}
+	z := 0.0
+	for _, m := range metrics {
+		rate, ok := m.rate(data)
+		if !ok {
+			continue
+		}
+		stats, ok := a.stats[m]
+		if !ok {
+			stats = &rollingStats{}
+			a.stats[m] = stats
+		}
+		if v, ok := stats.zScore(rate); ok && math.Abs(v) > math.Abs(z) {
+			z = v
+		}
+		stats.add(rate)
+	}
+	if math.Abs(z) > a.sigma {
+		a.onAnomaly(data, z)
+	}
+}
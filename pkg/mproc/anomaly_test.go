@@ -0,0 +1,48 @@
+package mproc
+
+import "testing"
+
+func TestAnomalyDetector(t *testing.T) {
+	var fired []float64
+	a := &anomalyDetector{sigma: 3, onAnomaly: func(_ TsCallData, z float64) { fired = append(fired, z) }}
+
+	// 均值 1100, 标准差 100
+	for i := range 20 {
+		a.check(TsCallData{BytesRxF: 1000 + 200*float64(i%2)})
+	}
+	if len(fired) != 0 {
+		t.Fatalf("stable series fired %v", fired)
+	}
+	a.check(TsCallData{BytesRxF: 2000})
+	if len(fired) != 1 || fired[0] != 9 {
+		t.Fatalf("outlier fired %v, want [9]", fired)
+	}
+}
+
+// 样本不足或完全平稳时没有标准差, 不做检测
+func TestAnomalyDetectorNeedsVariance(t *testing.T) {
+	fired := false
+	a := &anomalyDetector{sigma: 3, onAnomaly: func(TsCallData, float64) { fired = true }}
+	for range 20 {
+		a.check(TsCallData{BytesTxF: 1000})
+	}
+	a.check(TsCallData{BytesTxF: 5000})
+	if fired {
+		t.Fatal("fired on a flat series")
+	}
+}
+
+func TestWithAnomalySigma(t *testing.T) {
+	for _, opts := range [][]netDevOpts{
+		{WithAnomalySigma(2), WithAnomalyDetection(func(TsCallData, float64) {})},
+		{WithAnomalyDetection(func(TsCallData, float64) {}), WithAnomalySigma(2)},
+	} {
+		n := &netDev{args: &netDevArgs{}}
+		for _, opt := range opts {
+			opt(n)
+		}
+		if n.anomaly.sigma != 2 {
+			t.Fatalf("sigma = %v, want 2 regardless of option order", n.anomaly.sigma)
+		}
+	}
+}
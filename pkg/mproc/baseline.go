@@ -0,0 +1,67 @@
+package mproc
+
+import (
+	"slices"
+	"time"
+)
+
+// rateBaseline 最近 steps+1 次读取的快照, 速率按最早的快照到本次读取计算
+type rateBaseline struct {
+	steps int
+	ring  []baselineEntry
+}
+
+type baselineEntry struct {
+	stats   map[string]TsNetDev
+	elapsed time.Duration // 与前一个快照之间的间隔
+}
+
+// WithRateBaseline 速率按最近 n 个采样间隔计算: 本次读取与 n 次之前读取的差值除以其间经过的时间,
+// 比只与上一次比较更平滑, 但仍是真实的流量. Delta 字段仍是最近一个间隔的增量. n 小于 2 时只与上一次比较
+func WithRateBaseline(n int) netDevOpts {
+	return func(t *netDev) {
+		t.baseline = nil
+		if n > 1 {
+			t.baseline = &rateBaseline{steps: n}
+		}
+	}
+}
+
+// reset 重新读取基线时丢弃之前的快照
+func (b *rateBaseline) reset() {
+	b.ring = b.ring[:0]
+}
+
+// push 记录 prev 之后经过 elapsed 读取的 cur, 返回最多 steps 次读取之前的快照和其间经过的时间
+func (b *rateBaseline) push(prev, cur map[string]TsNetDev, elapsed time.Duration) (map[string]TsNetDev, time.Duration) {
+	if len(b.ring) == 0 {
+		b.ring = append(b.ring, baselineEntry{stats: prev})
+	}
+	b.ring = append(b.ring, baselineEntry{stats: cur, elapsed: elapsed})
+	if len(b.ring) > b.steps+1 {
+		b.ring = slices.Delete(b.ring, 0, 1)
+	}
+	var span time.Duration
+	for _, e := range b.ring[1:] {
+		span += e.elapsed
+	}
+	return b.ring[0].stats, span
+}
+
+// useRates 用 wide 中按多个间隔计算的速率替换 data 中的速率, 保留 data 的增量
+func useRates(data *TsCallData, wide TsCallData) {
+	data.BytesRx, data.BytesTx = wide.BytesRx, wide.BytesTx
+	data.BytesRxF, data.BytesTxF = wide.BytesRxF, wide.BytesTxF
+	data.PacketsRx, data.PacketsTx = wide.PacketsRx, wide.PacketsTx
+	data.ErrsRx, data.ErrsTx = wide.ErrsRx, wide.ErrsTx
+	data.DropRx, data.DropTx = wide.DropRx, wide.DropTx
+	data.UnfilteredBytesRx, data.UnfilteredBytesTx = wide.UnfilteredBytesRx, wide.UnfilteredBytesTx
+	data.Saturated = data.Saturated || wide.Saturated
+	for name, rate := range data.PerInterface {
+		if w, ok := wide.PerInterface[name]; ok {
+			rate.BytesRx, rate.BytesTx = w.BytesRx, w.BytesTx
+			rate.BytesRxF, rate.BytesTxF = w.BytesRxF, w.BytesTxF
+			data.PerInterface[name] = rate
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithRateBaseline(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	WithRateBaseline(2)(n)
+	counters := []int{0, 100, 300, 600, 1000}
+	// 第一次只有一个间隔, 之后按最近两个间隔计算速率; 增量仍是最近一个间隔的
+	wantRate := []int64{100, 150, 250, 350}
+	for i, want := range wantRate {
+		data := n.sample(snapshot("eth0", counters[i], 0), snapshot("eth0", counters[i+1], 0), sampleTiming{elapsed: time.Second})
+		if data.BytesRx != want || data.PerInterface["eth0"].BytesRx != want {
+			t.Fatalf("step %d: BytesRx = %d (eth0 %d), want %d", i+1, data.BytesRx, data.PerInterface["eth0"].BytesRx, want)
+		}
+		if delta := int64(counters[i+1] - counters[i]); data.DeltaRx != delta {
+			t.Fatalf("step %d: DeltaRx = %d, want %d", i+1, data.DeltaRx, delta)
+		}
+	}
+
+	// 重新读取基线后之前的快照不再参与计算
+	n.baseline.reset()
+	if data := n.sample(snapshot("eth0", 5000, 0), snapshot("eth0", 5500, 0), sampleTiming{elapsed: time.Second}); data.BytesRx != 500 {
+		t.Fatalf("after reset BytesRx = %d, want 500", data.BytesRx)
+	}
+}
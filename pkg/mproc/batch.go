@@ -0,0 +1,46 @@
+package mproc
+
+// batchSink 累积采样, 每 size 个调用一次 callback, 只在采样协程中访问
+type batchSink struct {
+	size     int
+	callback func(batch []TsCallData)
+	pending  []TsCallData
+}
+
+// WithBatch 每累积 n 个采样调用一次 WithBatchCallback 设置的函数, 默认为 1; 监控关闭时交付不足 n 个的剩余采样
+func WithBatch(n int) netDevOpts {
+	return func(t *netDev) {
+		t.args.BatchSize = n
+	}
+}
+
+// WithBatchCallback 以按采样顺序排列的一批采样调用 callback, 适合批量写入下游存储;
+// 与 WithCallback 独立, 两者都会被调用. callback 在采样协程中执行, 可以保留 batch
+func WithBatchCallback(callback func(batch []TsCallData)) netDevOpts {
+	return func(t *netDev) {
+		t.batch = &batchSink{callback: callback}
+	}
+}
+
+func (b *batchSink) Write(data TsCallData) error {
+	b.pending = append(b.pending, data)
+	if len(b.pending) >= b.size {
+		b.flush()
+	}
+	return nil
+}
+
+// Close 交付剩余的采样
+func (b *batchSink) Close() error {
+	b.flush()
+	return nil
+}
+
+func (b *batchSink) flush() {
+	if len(b.pending) == 0 {
+		return
+	}
+	batch := b.pending
+	b.pending = make([]TsCallData, 0, b.size)
+	b.callback(batch)
+}
@@ -0,0 +1,41 @@
+package mproc
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithBatch(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]TsCallData
+	n := newFIFONetDev(t, 50*time.Millisecond, WithBatch(2), WithBatchCallback(func(batch []TsCallData) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, batch)
+	}))
+	n.feed(netDevFile("eth0", 0, 0))
+	for i := 1; i <= 4; i++ {
+		n.feed(netDevFile("eth0", i*1000, 0))
+		n.next()
+	}
+	n.feed(netDevFile("eth0", 4000, 0)) // 第 5 个采样, 速率为 0
+	n.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 3 || len(batches[0]) != 2 || len(batches[1]) != 2 || len(batches[2]) != 1 {
+		t.Fatalf("batch sizes = %v, want 2, 2 and the partial 1 on Close", batchSizes(batches))
+	}
+	if batches[0][0].DeltaRx != 1000 || batches[1][1].DeltaRx != 1000 || batches[2][0].DeltaRx != 0 {
+		t.Fatalf("batches out of order: %+v", batches)
+	}
+}
+
+func batchSizes(batches [][]TsCallData) []int {
+	sizes := make([]int, len(batches))
+	for i, b := range batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
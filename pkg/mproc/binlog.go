@@ -0,0 +1,80 @@
+package mproc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// binaryRecordSize 每条记录的字节数, 6 个小端 int64:
+//
+//	sampled_at_unix_nano, interval_ns, bytes_rx, bytes_tx, packets_rx, packets_tx
+const binaryRecordSize = 6 * 8
+
+// binaryLogWriter 每个采样写一条定长记录
+type binaryLogWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf [binaryRecordSize]byte
+}
+
+func (b *binaryLogWriter) Write(data TsCallData) error {
+	var at int64
+	if !data.SampledAt.IsZero() {
+		at = data.SampledAt.UnixNano()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, v := range []int64{at, int64(data.Interval), data.BytesRx, data.BytesTx, data.PacketsRx, data.PacketsTx} {
+		binary.LittleEndian.PutUint64(b.buf[i*8:], uint64(v))
+	}
+	_, err := b.w.Write(b.buf[:])
+	return err
+}
+
+// Close 不关闭 w, 与 WithNDJSON 一致由调用方管理
+func (b *binaryLogWriter) Close() error {
+	return nil
+}
+
+// WithBinaryLog 每个采样向 w 写入一条 48 字节的定长记录 (采样时间, 间隔, 收发字节速率, 收发包速率),
+// 用 ReadBinaryLog 读回; 只保留汇总速率, 适合长时间抓取. 监控关闭时不关闭 w
+func WithBinaryLog(w io.Writer) netDevOpts {
+	return func(t *netDev) {
+		WithSink(&binaryLogWriter{w: w})(t)
+	}
+}
+
+// ReadBinaryLog 读取 WithBinaryLog 写入的全部记录; 末尾不完整的记录返回 io.ErrUnexpectedEOF,
+// 同时返回此前读到的记录
+func ReadBinaryLog(r io.Reader) ([]TsCallData, error) {
+	br := bufio.NewReader(r)
+	var out []TsCallData
+	var buf [binaryRecordSize]byte
+	for {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return out, err
+		}
+		var v [6]int64
+		for i := range v {
+			v[i] = int64(binary.LittleEndian.Uint64(buf[i*8:]))
+		}
+		data := TsCallData{
+			Interval: time.Duration(v[1]),
+			BytesRx:  v[2], BytesTx: v[3],
+			BytesRxF: float64(v[2]), BytesTxF: float64(v[3]),
+			PacketsRx: v[4], PacketsTx: v[5],
+		}
+		if v[0] != 0 {
+			data.SampledAt = time.Unix(0, v[0])
+			data.PrevSampledAt = data.SampledAt.Add(-data.Interval)
+		}
+		out = append(out, data)
+	}
+}
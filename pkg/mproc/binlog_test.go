@@ -0,0 +1,73 @@
+package mproc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBinaryLogRoundTrip(t *testing.T) {
+	at := time.Unix(1700000000, 250)
+	samples := []TsCallData{
+		{Interval: time.Second, BytesRx: 1000, BytesTx: 2000, PacketsRx: 10, PacketsTx: 20,
+			PrevSampledAt: at.Add(-time.Second), SampledAt: at},
+		{Interval: 100 * time.Millisecond},
+		{BytesRx: -1, PacketsTx: 1 << 40},
+	}
+
+	var buf bytes.Buffer
+	w := &binaryLogWriter{w: &buf}
+	for _, s := range samples {
+		if err := w.Write(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() != len(samples)*binaryRecordSize {
+		t.Fatalf("wrote %d bytes, want %d", buf.Len(), len(samples)*binaryRecordSize)
+	}
+
+	got, err := ReadBinaryLog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range samples {
+		samples[i].BytesRxF, samples[i].BytesTxF = float64(samples[i].BytesRx), float64(samples[i].BytesTx)
+	}
+	if !reflect.DeepEqual(got, samples) {
+		t.Fatalf("ReadBinaryLog = %+v, want %+v", got, samples)
+	}
+}
+
+func TestBinaryLogTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	w := &binaryLogWriter{w: &buf}
+	w.Write(TsCallData{BytesRx: 1})
+	w.Write(TsCallData{BytesRx: 2})
+	got, err := ReadBinaryLog(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if len(got) != 1 || got[0].BytesRx != 1 {
+		t.Fatalf("records = %+v, want the first complete record", got)
+	}
+}
+
+func TestWithBinaryLog(t *testing.T) {
+	var buf bytes.Buffer
+	n := newFIFONetDev(t, 50*time.Millisecond, WithBinaryLog(&buf))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	want := n.next()
+	n.Close()
+
+	got, err := ReadBinaryLog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].BytesRx != want.BytesRx || !got[0].SampledAt.Equal(want.SampledAt) || got[0].Interval != want.Interval {
+		t.Fatalf("records = %+v, want one record of %+v", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package mproc
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BondMode bond 接口的统计方式, 见 WithBondAggregation
+type BondMode int
+
+const (
+	BondOff    BondMode = iota // 不识别 bond, bond 和成员都计入汇总, 会重复计算
+	BondDevice                 // 只统计 bond 接口, 忽略成员
+	BondSlaves                 // 只统计成员, 忽略 bond 接口
+	BondBoth                   // 汇总只计入 bond 接口, 成员单独出现在 PerInterface 中并标记所属的 bond
+)
+
+// bondSlaves 从 sysfs 读取 cur 中 bond 接口的成员, 返回成员到 bond 接口的映射
+func bondSlaves(root string, cur map[string]TsNetDev) map[string]string {
+	slaves := make(map[string]string)
+	for name := range cur {
+		b, err := os.ReadFile(filepath.Join(root, "class", "net", name, "bonding", "slaves"))
+		if err != nil {
+			continue // 不是 bond 接口
+		}
+		for _, slave := range strings.Fields(string(b)) {
+			slaves[slave] = name
+		}
+	}
+	return slaves
+}
+
+// diffBond 按 mode 计算速率, 保证同一份流量在汇总中只计算一次
+func diffBond(prev, cur map[string]TsNetDev, elapsed time.Duration, metrics Metric, mode BondMode, root string) TsCallData {
+	slaves := bondSlaves(root, cur)
+	if len(slaves) == 0 {
+		return diff(prev, cur, elapsed, metrics)
+	}
+	isSlave := func(name string, _ TsNetDev) bool {
+		_, ok := slaves[name]
+		return ok
+	}
+	isBond := func(name string, _ TsNetDev) bool {
+		for _, bond := range slaves {
+			if bond == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	devices := maps.Clone(cur)
+	maps.DeleteFunc(devices, isSlave)
+	switch mode {
+	case BondDevice:
+		return diff(prev, devices, elapsed, metrics)
+	case BondSlaves:
+		members := maps.Clone(cur)
+		maps.DeleteFunc(members, isBond)
+		return diff(prev, members, elapsed, metrics)
+	}
+
+	data := diff(prev, devices, elapsed, metrics)
+	if data.PerInterface != nil {
+		members := maps.Clone(cur)
+		maps.DeleteFunc(members, func(name string, s TsNetDev) bool { return !isSlave(name, s) })
+		for name, rate := range diff(prev, members, elapsed, metrics).PerInterface {
+			rate.Bond = slaves[name]
+			data.PerInterface[name] = rate
+		}
+	}
+	return data
+}
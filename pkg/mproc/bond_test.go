@@ -0,0 +1,50 @@
+package mproc
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBondAggregation(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "class", "net", "bond0", "bonding", "slaves"), "eth0 eth1\n")
+
+	prev := snapshot("bond0", 0, 0, "eth0", 0, 0, "eth1", 0, 0, "lo", 0, 0)
+	cur := snapshot("bond0", 3000, 300, "eth0", 1000, 100, "eth1", 2000, 200, "lo", 50, 50)
+
+	cases := []struct {
+		mode       BondMode
+		rx         int64
+		interfaces []string
+	}{
+		{BondOff, 6050, []string{"bond0", "eth0", "eth1", "lo"}},
+		{BondDevice, 3050, []string{"bond0", "lo"}},
+		{BondSlaves, 3050, []string{"eth0", "eth1", "lo"}},
+		{BondBoth, 3050, []string{"bond0", "eth0", "eth1", "lo"}},
+	}
+	for _, c := range cases {
+		n := &netDev{args: &netDevArgs{Callback: func(TsCallData) {}, Metrics: MetricAll, SysfsRoot: root}}
+		WithBondAggregation(c.mode)(n)
+		data := n.sample(prev, cur, sampleTiming{elapsed: time.Second})
+		if data.BytesRx != c.rx {
+			t.Errorf("mode %d: BytesRx = %d, want %d", c.mode, data.BytesRx, c.rx)
+		}
+		if len(data.PerInterface) != len(c.interfaces) {
+			t.Errorf("mode %d: PerInterface = %v, want %v", c.mode, data.PerInterface, c.interfaces)
+		}
+		for _, name := range c.interfaces {
+			if _, ok := data.PerInterface[name]; !ok {
+				t.Errorf("mode %d: missing %s in PerInterface", c.mode, name)
+			}
+		}
+		if c.mode == BondBoth {
+			if got := data.PerInterface["eth1"]; got.Bond != "bond0" || got.BytesRx != 2000 {
+				t.Errorf("eth1 = %+v, want BytesRx 2000 in bond0", got)
+			}
+			if got := data.PerInterface["bond0"]; got.Bond != "" {
+				t.Errorf("bond0.Bond = %q, want empty", got.Bond)
+			}
+		}
+	}
+}
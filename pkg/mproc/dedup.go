@@ -0,0 +1,51 @@
+package mproc
+
+import (
+	"reflect"
+	"time"
+)
+
+// dedupMaxIdle 默认的最长抑制时间
+const dedupMaxIdle = time.Minute
+
+// dedup 抑制与上一次输出相同的采样, 需要 WithDedup, 只在采样协程中访问
+type dedup struct {
+	maxIdle time.Duration
+	last    *TsCallData // 上一次输出的采样, 已去掉与时间相关的字段
+	at      time.Time   // 上一次输出的时间
+}
+
+// WithDedup 采样与上一次输出的采样相同时不回调也不写入输出, 适合空闲接口较多、按事件存储的输出;
+// 比较时忽略 Interval、ReadSkew、ReadDuration、MonotonicElapsed、MissedIntervals、采样时间和平均速率等随时间变化的字段.
+// 至少每 WithDedupMaxIdle (默认 1 分钟) 输出一次, 表示监控仍在运行
+func WithDedup(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.dedup = nil
+		if enabled {
+			t.dedup = &dedup{maxIdle: dedupMaxIdle}
+		}
+	}
+}
+
+// WithDedupMaxIdle 设置 WithDedup 连续抑制的最长时间, 需要同时使用 WithDedup
+func WithDedupMaxIdle(d time.Duration) netDevOpts {
+	return func(t *netDev) {
+		if t.dedup == nil {
+			t.dedup = &dedup{}
+		}
+		t.dedup.maxIdle = d
+	}
+}
+
+// suppress 判断 at 时的采样是否与上一次输出相同且未超过最长抑制时间, 不抑制时记录为上一次输出
+func (d *dedup) suppress(data TsCallData, at time.Time) bool {
+	key := data
+	key.Interval, key.ReadSkew, key.ReadDuration, key.MonotonicElapsed, key.MissedIntervals = 0, 0, 0, 0, 0
+	key.PrevSampledAt, key.SampledAt = time.Time{}, time.Time{}
+	key.AvgBytesRx, key.AvgBytesTx = 0, 0
+	if d.last != nil && reflect.DeepEqual(*d.last, key) && at.Sub(d.at) < d.maxIdle {
+		return true
+	}
+	d.last, d.at = &key, at
+	return false
+}
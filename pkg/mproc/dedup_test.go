@@ -0,0 +1,29 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	var got []TsCallData
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(data TsCallData) { got = append(got, data) }}}
+	WithDedup(true)(n)
+	WithDedupMaxIdle(3 * time.Second)(n)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	idle := snapshot("eth0", 100, 100)
+	// 空闲接口每秒一次采样, 第 0 秒输出, 之后相同的采样被抑制, 第 3、6 秒作为心跳输出
+	for i := range 8 {
+		n.sample(idle, idle, sampleTiming{elapsed: time.Second, measured: time.Duration(i) * time.Millisecond, at: base.Add(time.Duration(i) * time.Second)})
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d callbacks, want 3 (first and two heartbeats)", len(got))
+	}
+
+	// 数据变化后立即输出
+	n.sample(idle, snapshot("eth0", 600, 100), sampleTiming{elapsed: time.Second, at: base.Add(8 * time.Second)})
+	if len(got) != 4 || got[3].DeltaRx != 500 {
+		t.Fatalf("callbacks = %+v, want the changed sample", got)
+	}
+}
@@ -0,0 +1,29 @@
+package mproc
+
+import "math"
+
+// DeltaPercentFromZero 上一次采样的速率为 0 而本次不为 0 时 DeltaPercentRx/DeltaPercentTx 的值,
+// 变化百分比没有定义; 不使用 +Inf, 以便 JSON 等输出可以编码
+const DeltaPercentFromZero = math.MaxFloat64
+
+// deltaPercent 计算速率相对上一次采样的变化百分比, 第一次采样为 0
+func (n *netDev) deltaPercent(data *TsCallData) {
+	last := n.lastRate
+	n.lastRate.rx, n.lastRate.tx, n.lastRate.ok = data.BytesRxF, data.BytesTxF, true
+	if !last.ok {
+		return
+	}
+	data.DeltaPercentRx = percentChange(last.rx, data.BytesRxF)
+	data.DeltaPercentTx = percentChange(last.tx, data.BytesTxF)
+}
+
+func percentChange(prev, cur float64) float64 {
+	switch {
+	case prev != 0:
+		return (cur - prev) / prev * 100
+	case cur != 0:
+		return DeltaPercentFromZero
+	default:
+		return 0
+	}
+}
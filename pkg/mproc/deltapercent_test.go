@@ -0,0 +1,30 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeltaPercent(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	steps := []struct {
+		rx, tx       int
+		pctRx, pctTx float64
+	}{
+		{0, 0, 0, 0},                          // 第一次采样没有可比较的速率
+		{1000, 0, DeltaPercentFromZero, 0},    // 接收从 0 开始; 发送仍为 0
+		{2500, 500, 50, DeltaPercentFromZero}, // 1500/s: +50%; 发送从 0 开始
+		{3250, 1500, -50, 100},                // 750/s: -50%; 500 -> 1000: +100%
+		{3250, 1500, -100, -100},              // 都降为 0
+		{3250, 1500, 0, 0},                    // 0 -> 0
+	}
+	prev := snapshot("eth0", 0, 0)
+	for i, s := range steps {
+		cur := snapshot("eth0", s.rx, s.tx)
+		data := n.sample(prev, cur, sampleTiming{elapsed: time.Second})
+		if data.DeltaPercentRx != s.pctRx || data.DeltaPercentTx != s.pctTx {
+			t.Fatalf("step %d: DeltaPercent = %v/%v, want %v/%v", i, data.DeltaPercentRx, data.DeltaPercentTx, s.pctRx, s.pctTx)
+		}
+		prev = cur
+	}
+}
@@ -0,0 +1,25 @@
+package mproc
+
+// WithDerived 注册名为 name 的派生指标, 每次采样以 fn 的结果填充 Derived[name], 并随采样写入输出,
+// 例如 (ErrsRx + DropRx) / PacketsRx. fn 看到的采样中还没有 Derived; 应返回有限值
+// (例如分母为 0 时返回 0), NaN 和 Inf 无法编码为 JSON. 同名的派生指标会被替换
+func WithDerived(name string, fn func(data TsCallData) float64) netDevOpts {
+	return func(t *netDev) {
+		if t.args.Derived == nil {
+			t.args.Derived = map[string]func(data TsCallData) float64{}
+		}
+		t.args.Derived[name] = fn
+	}
+}
+
+// derive 计算所有派生指标
+func (n *netDev) derive(data *TsCallData) {
+	if len(n.args.Derived) == 0 {
+		return
+	}
+	derived := make(map[string]float64, len(n.args.Derived))
+	for name, fn := range n.args.Derived {
+		derived[name] = fn(*data)
+	}
+	data.Derived = derived
+}
@@ -0,0 +1,29 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithDerived(t *testing.T) {
+	sink := &fakeSink{}
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	WithSink(sink)(n)
+	WithDerived("rx_loss_ratio", func(data TsCallData) float64 {
+		if data.PacketsRx == 0 {
+			return 0
+		}
+		return float64(data.ErrsRx+data.DropRx) / float64(data.PacketsRx)
+	})(n)
+
+	counters := func(packets, errs, drop int64) map[string]TsNetDev {
+		return map[string]TsNetDev{"eth0": {Name: "eth0", Receive: TsNetDevInfo{Packets: packets, Errs: errs, Drop: drop}}}
+	}
+	data := n.sample(counters(0, 0, 0), counters(200, 2, 3), sampleTiming{elapsed: time.Second})
+	if got := data.Derived["rx_loss_ratio"]; got != 0.025 {
+		t.Fatalf("rx_loss_ratio = %v, want 0.025", got)
+	}
+	if got := sink.samples[0].Derived["rx_loss_ratio"]; got != 0.025 {
+		t.Fatalf("sink rx_loss_ratio = %v, want 0.025", got)
+	}
+}
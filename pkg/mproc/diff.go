@@ -0,0 +1,133 @@
+package mproc
+
+import (
+	"math"
+	"time"
+)
+
+// Diff 计算两次快照之间的速率, 不依赖采样循环, 可以用于任意时间获取的快照
+//
+// 汇总只包含两次快照中都存在的接口, 新出现的接口只作为基线;
+// elapsed 不为正数时只计算差值, 速率为 0
+func Diff(prev, cur map[string]TsNetDev, elapsed time.Duration) TsCallData {
+	return diff(prev, cur, elapsed, MetricAll)
+}
+
+// diff 只计算 metrics 中的指标, 其他字段保持零值
+func diff(prev, cur map[string]TsNetDev, elapsed time.Duration, metrics Metric) TsCallData {
+	data := TsCallData{Interval: elapsed}
+	if metrics.Has(MetricPerInterface) {
+		data.PerInterface = make(map[string]TsInterfaceRate, len(cur))
+	}
+	var sat saturation
+	counters := diffCounters{sat: &sat, metrics: metrics}
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok {
+			continue
+		}
+		var rate TsInterfaceRate
+		if metrics.Has(MetricBytesRx) {
+			rate.DeltaRx = counterDelta(p.Receive.Bytes, c.Receive.Bytes)
+			data.DeltaRx = sat.add(data.DeltaRx, rate.DeltaRx)
+		}
+		if metrics.Has(MetricBytesTx) {
+			rate.DeltaTx = counterDelta(p.Transmit.Bytes, c.Transmit.Bytes)
+			data.DeltaTx = sat.add(data.DeltaTx, rate.DeltaTx)
+		}
+		if data.PerInterface != nil {
+			rate.BytesRxF, rate.BytesTxF = perSecond(rate.DeltaRx, elapsed), perSecond(rate.DeltaTx, elapsed)
+			rate.BytesRx, rate.BytesTx = sat.toInt(rate.BytesRxF), sat.toInt(rate.BytesTxF)
+			data.PerInterface[name] = rate
+		}
+		counters.add(p, c)
+	}
+	data.PacketsRx, data.PacketsTx = sat.toInt(perSecond(counters.packetsRx, elapsed)), sat.toInt(perSecond(counters.packetsTx, elapsed))
+	data.ErrsRx, data.ErrsTx = sat.toInt(perSecond(counters.errsRx, elapsed)), sat.toInt(perSecond(counters.errsTx, elapsed))
+	data.DropRx, data.DropTx = sat.toInt(perSecond(counters.dropRx, elapsed)), sat.toInt(perSecond(counters.dropTx, elapsed))
+	data.BytesRxF, data.BytesTxF = perSecond(data.DeltaRx, elapsed), perSecond(data.DeltaTx, elapsed)
+	data.BytesRx, data.BytesTx = sat.toInt(data.BytesRxF), sat.toInt(data.BytesTxF)
+	data.Saturated = sat.saturated
+	return data
+}
+
+// diffCounters 包数、错误数和丢包数的增量之和
+type diffCounters struct {
+	packetsRx, packetsTx int64
+	errsRx, errsTx       int64
+	dropRx, dropTx       int64
+	sat                  *saturation
+	metrics              Metric
+}
+
+func (d *diffCounters) add(prev, cur TsNetDev) {
+	for _, c := range []struct {
+		metric    Metric
+		total     *int64
+		prev, cur int64
+	}{
+		{MetricPacketsRx, &d.packetsRx, prev.Receive.Packets, cur.Receive.Packets},
+		{MetricPacketsTx, &d.packetsTx, prev.Transmit.Packets, cur.Transmit.Packets},
+		{MetricErrsRx, &d.errsRx, prev.Receive.Errs, cur.Receive.Errs},
+		{MetricErrsTx, &d.errsTx, prev.Transmit.Errs, cur.Transmit.Errs},
+		{MetricDropRx, &d.dropRx, prev.Receive.Drop, cur.Receive.Drop},
+		{MetricDropTx, &d.dropTx, prev.Transmit.Drop, cur.Transmit.Drop},
+	} {
+		if d.metrics.Has(c.metric) {
+			*c.total = d.sat.add(*c.total, counterDelta(c.prev, c.cur))
+		}
+	}
+}
+
+// counterWrapMaxDelta 按 32 位回绕计算时增量的上限 (1 GiB). 计数器从 2 GiB 到 4 GiB 之间变小也可能是
+// 64 位计数器被重置, 回绕后的增量达到 32 位范围的 1/4 时不可信, 按重置处理
+const counterWrapMaxDelta = 1 << 30
+
+// counterDelta 计算计数器的增量: 从 32 位上限附近回绕并且增量可信时按回绕计算, 其他变小的情况 (如接口重置) 按 0 处理
+func counterDelta(prev, cur int64) int64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	if prev <= math.MaxUint32 && cur <= math.MaxUint32 {
+		if wrapped := cur + math.MaxUint32 + 1 - prev; wrapped < counterWrapMaxDelta {
+			return wrapped
+		}
+	}
+	return 0
+}
+
+// counterReset 判断计数器是否被重置: 变小并且不像 32 位回绕
+func counterReset(prev, cur int64) bool {
+	return cur < prev && counterDelta(prev, cur) == 0
+}
+
+// saturation 饱和运算, 结果超出 int64 时取 math.MaxInt64 并记录
+type saturation struct {
+	saturated bool
+}
+
+// add 两个非负数相加
+func (s *saturation) add(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		s.saturated = true
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// toInt 将非负的浮点数截断为整数, float64(math.MaxInt64) 即 2^63 已超出 int64
+func (s *saturation) toInt(f float64) int64 {
+	if f >= math.MaxInt64 {
+		s.saturated = true
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+// perSecond 按浮点秒数换算为每秒速率, 小于 1 秒的间隔不会出现除以 0
+func perSecond(delta int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(delta) / elapsed.Seconds()
+}
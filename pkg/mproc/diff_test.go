@@ -0,0 +1,126 @@
+package mproc
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func snapshot(ifaces ...any) map[string]TsNetDev {
+	stats := map[string]TsNetDev{}
+	for i := 0; i+2 < len(ifaces); i += 3 {
+		name := ifaces[i].(string)
+		stats[name] = TsNetDev{
+			Name:     name,
+			Receive:  TsNetDevInfo{Bytes: int64(ifaces[i+1].(int))},
+			Transmit: TsNetDevInfo{Bytes: int64(ifaces[i+2].(int))},
+		}
+	}
+	return stats
+}
+
+func TestDiff(t *testing.T) {
+	data := Diff(snapshot("eth0", 1000, 0, "eth1", 0, 0), snapshot("eth0", 4000, 500, "eth1", 1000, 0), 2*time.Second)
+	if data.BytesRx != 2000 || data.BytesTx != 250 || data.DeltaRx != 4000 || data.Interval != 2*time.Second {
+		t.Fatalf("aggregate = %+v, want 2000/250 over 2s", data)
+	}
+	if r := data.PerInterface["eth1"]; r.BytesRx != 500 || r.DeltaRx != 1000 {
+		t.Fatalf("eth1 = %+v, want 500/s", r)
+	}
+}
+
+func TestDiffWrapped(t *testing.T) {
+	data := Diff(snapshot("eth0", math.MaxUint32-99, 0), snapshot("eth0", 100, 0), time.Second)
+	if data.DeltaRx != 200 {
+		t.Fatalf("DeltaRx = %d, want 200 across the 32-bit wrap", data.DeltaRx)
+	}
+	// 远离上限时变小视为重置
+	data = Diff(snapshot("eth0", 5000, 0), snapshot("eth0", 100, 0), time.Second)
+	if data.DeltaRx != 0 {
+		t.Fatalf("DeltaRx = %d, want 0 after a reset", data.DeltaRx)
+	}
+	// 从 3 GiB 变小时回绕后的增量超过 1 GiB, 是 64 位计数器的重置而不是回绕
+	data = Diff(snapshot("eth0", 3<<30, 0), snapshot("eth0", 1000, 0), time.Second)
+	if data.DeltaRx != 0 {
+		t.Fatalf("DeltaRx = %d, want 0 after a reset from 3 GiB", data.DeltaRx)
+	}
+}
+
+// 新出现的接口只作为基线, 消失的接口不计入汇总
+func TestDiffInterfaceSetChanged(t *testing.T) {
+	data := Diff(snapshot("eth0", 0, 0, "eth1", 9000, 0), snapshot("eth0", 1000, 0, "eth2", 5000, 0), time.Second)
+	if data.BytesRx != 1000 {
+		t.Fatalf("BytesRx = %d, want 1000 from eth0 only", data.BytesRx)
+	}
+	if len(data.PerInterface) != 1 {
+		t.Fatalf("PerInterface = %v, want only eth0", data.PerInterface)
+	}
+}
+
+func TestDiffZeroElapsed(t *testing.T) {
+	data := Diff(snapshot("eth0", 0, 0), snapshot("eth0", 1000, 0), 0)
+	if data.DeltaRx != 1000 || data.BytesRxF != 0 {
+		t.Fatalf("data = %+v, want delta 1000 with zero rate", data)
+	}
+}
+
+func TestDiffPackets(t *testing.T) {
+	prev := snapshot("eth0", 0, 0)
+	cur := snapshot("eth0", 0, 0)
+	c := cur["eth0"]
+	c.Receive.Packets, c.Transmit.Packets, c.Receive.Errs, c.Transmit.Drop = 20, 10, 4, 2
+	cur["eth0"] = c
+	data := Diff(prev, cur, 2*time.Second)
+	if data.PacketsRx != 10 || data.PacketsTx != 5 || data.ErrsRx != 2 || data.DropTx != 1 {
+		t.Fatalf("data = %+v, want packets 10/5, errs rx 2, drop tx 1", data)
+	}
+}
+
+func TestDiffSaturated(t *testing.T) {
+	prev := snapshot("eth0", 0, 0, "eth1", 0, 0)
+	cur := snapshot("eth0", 0, 0, "eth1", 0, 0)
+	for _, name := range []string{"eth0", "eth1"} {
+		c := cur[name]
+		c.Receive.Bytes = math.MaxInt64 - 1
+		cur[name] = c
+	}
+	data := Diff(prev, cur, time.Second)
+	if !data.Saturated || data.DeltaRx != math.MaxInt64 || data.BytesRx != math.MaxInt64 {
+		t.Fatalf("data = %+v, want the sum saturated at MaxInt64", data)
+	}
+
+	// 单个增量不溢出, 但换算为每秒速率后超出 int64
+	data = Diff(snapshot("eth0", 0, 0), snapshot("eth0", 1<<62, 0), time.Nanosecond)
+	if !data.Saturated || data.BytesRx != math.MaxInt64 || data.BytesRxF <= 0 {
+		t.Fatalf("data = %+v, want the rate saturated at MaxInt64", data)
+	}
+
+	if data := Diff(snapshot("eth0", 0, 0), snapshot("eth0", 1000, 0), time.Second); data.Saturated {
+		t.Fatal("normal diff reported as saturated")
+	}
+}
+
+func TestDiffMetrics(t *testing.T) {
+	prev := snapshot("eth0", 0, 0)
+	cur := snapshot("eth0", 1000, 2000)
+	c := cur["eth0"]
+	c.Receive.Packets = 10
+	cur["eth0"] = c
+
+	data := diff(prev, cur, time.Second, MetricBytesRx)
+	if data.BytesRx != 1000 {
+		t.Fatalf("BytesRx = %d, want 1000", data.BytesRx)
+	}
+	if data.BytesTx != 0 || data.DeltaTx != 0 || data.PacketsRx != 0 || data.PerInterface != nil {
+		t.Fatalf("data = %+v, want only BytesRx computed", data)
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Callback: func(TsCallData) {}}}
+	WithMetrics(MetricBytesRx)(n)
+	data := n.sample(snapshot("eth0", 0, 0), snapshot("eth0", 1000, 2000), sampleTiming{elapsed: time.Second})
+	if data.BytesRx != 1000 || data.BytesTx != 0 || data.AvgBytesRx != 0 || data.PerInterface != nil {
+		t.Fatalf("data = %+v, want only BytesRx populated", data)
+	}
+}
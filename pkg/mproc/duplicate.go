@@ -0,0 +1,71 @@
+package mproc
+
+import (
+	"fmt"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// DuplicateMode WithPaths 的多个文件中出现同名接口时的处理方式
+type DuplicateMode int
+
+const (
+	DuplicatePrefix DuplicateMode = iota // 后面文件中的同名接口改名为 "<文件序号>/<接口名>", 序号从 0 开始
+	DuplicateSum                         // 同名接口的计数器相加, 第一次出现时记录警告
+	DuplicateError                       // 返回错误, 本次采样被跳过
+)
+
+// WithDuplicateInterfaces 设置 WithPaths 的多个文件中出现同名接口 (例如不同命名空间中的 eth0) 时的处理方式,
+// 默认为 DuplicatePrefix; 任何方式都不会因为覆盖而丢失字节数
+func WithDuplicateInterfaces(mode DuplicateMode) netDevOpts {
+	return func(t *netDev) {
+		t.args.Duplicates = mode
+	}
+}
+
+// mergeStats 将第 index 个文件的接口合并到 items
+func (n *netDev) mergeStats(items, stats map[string]TsNetDev, index int) error {
+	for name, s := range stats {
+		existing, ok := items[name]
+		if !ok {
+			items[name] = s
+			continue
+		}
+		switch n.args.Duplicates {
+		case DuplicateError:
+			return fmt.Errorf("duplicate interface %q in %s", name, n.args.Paths[index])
+		case DuplicateSum:
+			if !n.duplicates[name] {
+				if n.duplicates == nil {
+					n.duplicates = make(map[string]bool)
+				}
+				n.duplicates[name] = true
+				if !n.args.Quiet {
+					mlog.Warn(mlog.H{"msg": "duplicate interface, counters are summed", "interface": name, "path": n.args.Paths[index]})
+				}
+			}
+			existing.Receive = addNetDevInfo(existing.Receive, s.Receive)
+			existing.Transmit = addNetDevInfo(existing.Transmit, s.Transmit)
+			items[name] = existing
+		default:
+			s.Name = fmt.Sprintf("%d/%s", index, name)
+			items[s.Name] = s
+		}
+	}
+	return nil
+}
+
+func addNetDevInfo(a, b TsNetDevInfo) TsNetDevInfo {
+	return TsNetDevInfo{
+		Bytes:      a.Bytes + b.Bytes,
+		Packets:    a.Packets + b.Packets,
+		Errs:       a.Errs + b.Errs,
+		Drop:       a.Drop + b.Drop,
+		FIFO:       a.FIFO + b.FIFO,
+		Frame:      a.Frame + b.Frame,
+		Compressed: a.Compressed + b.Compressed,
+		Multicast:  a.Multicast + b.Multicast,
+		Colls:      a.Colls + b.Colls,
+		Carrier:    a.Carrier + b.Carrier,
+	}
+}
@@ -0,0 +1,53 @@
+package mproc
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDuplicateInterfaces(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	writeFile(t, a, netDevFile("eth0", 1000, 10, "lo", 5, 5))
+	writeFile(t, b, netDevFile("eth0", 2000, 20))
+
+	read := func(mode DuplicateMode) (map[string]TsNetDev, error) {
+		n := &netDev{args: &netDevArgs{Paths: []string{a, b}, Duplicates: mode}}
+		stats, _, err := n.readNetDev()
+		return stats, err
+	}
+
+	stats, err := read(DuplicatePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["eth0"].Receive.Bytes != 1000 || stats["1/eth0"].Receive.Bytes != 2000 || stats["1/eth0"].Name != "1/eth0" {
+		t.Fatalf("prefix: stats = %+v, want eth0 and 1/eth0", stats)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("prefix: got %d interfaces, want 3", len(stats))
+	}
+
+	stats, err = read(DuplicateSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stats["eth0"]; got.Receive.Bytes != 3000 || got.Transmit.Bytes != 30 || len(stats) != 2 {
+		t.Fatalf("sum: stats = %+v, want eth0 counters summed", stats)
+	}
+
+	if _, err := read(DuplicateError); err == nil {
+		t.Fatal("error mode: want an error for the duplicate eth0")
+	}
+
+	// 汇总中两个 eth0 的字节数都被计入
+	prev := map[string]TsNetDev{}
+	for name, s := range stats {
+		s.Receive.Bytes = 0
+		prev[name] = s
+	}
+	if data := Diff(prev, stats, time.Second); data.DeltaRx != 3005 {
+		t.Fatalf("sum: DeltaRx = %d, want 3005", data.DeltaRx)
+	}
+}
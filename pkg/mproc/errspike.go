@@ -0,0 +1,51 @@
+package mproc
+
+import "time"
+
+// errorSpikes 每个接口的错误和丢包速率超过下限时回调一次, 回到下限以下后才会再次回调, 只在采样协程中访问
+type errorSpikes struct {
+	callback func(iface string, errsPerSec, dropPerSec int64)
+	active   map[string]bool // 正处于超过下限状态的接口
+}
+
+// WithErrorSpikeCallback 每次采样计算每个接口的错误 (收发 errs 之和) 和丢包 (收发 drop 之和) 速率,
+// 任一速率超过下限 (默认 0, 见 WithErrorSpikeFloor) 时调用 callback, 用于找出 bond 中出问题的网卡或坏掉的网线.
+// 持续超过时不重复调用, 两个速率都回到下限以下后重新开始检测; 不受 WithMetrics 影响
+func WithErrorSpikeCallback(callback func(iface string, errsPerSec, dropPerSec int64)) netDevOpts {
+	return func(t *netDev) {
+		t.spikes = &errorSpikes{callback: callback, active: make(map[string]bool)}
+	}
+}
+
+// WithErrorSpikeFloor 设置 WithErrorSpikeCallback 的下限, 单位为每秒的错误或丢包数
+func WithErrorSpikeFloor(perSec int64) netDevOpts {
+	return func(t *netDev) {
+		t.args.ErrorSpikeFloor = perSec
+	}
+}
+
+func (s *errorSpikes) check(prev, cur map[string]TsNetDev, elapsed time.Duration, floor int64) {
+	for name := range s.active {
+		if _, ok := cur[name]; !ok {
+			delete(s.active, name)
+		}
+	}
+	var sat saturation
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok {
+			continue
+		}
+		errs := counterDelta(p.Receive.Errs, c.Receive.Errs) + counterDelta(p.Transmit.Errs, c.Transmit.Errs)
+		drop := counterDelta(p.Receive.Drop, c.Receive.Drop) + counterDelta(p.Transmit.Drop, c.Transmit.Drop)
+		errsPerSec, dropPerSec := sat.toInt(perSecond(errs, elapsed)), sat.toInt(perSecond(drop, elapsed))
+		spiking := errsPerSec > floor || dropPerSec > floor
+		switch {
+		case spiking && !s.active[name]:
+			s.active[name] = true
+			s.callback(name, errsPerSec, dropPerSec)
+		case !spiking:
+			delete(s.active, name)
+		}
+	}
+}
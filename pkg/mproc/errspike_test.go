@@ -0,0 +1,39 @@
+package mproc
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithErrorSpikeCallback(t *testing.T) {
+	var events []string
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	WithErrorSpikeCallback(func(iface string, errs, drop int64) {
+		events = append(events, fmt.Sprintf("%s %d %d", iface, errs, drop))
+	})(n)
+	WithErrorSpikeFloor(5)(n)
+
+	// bond 的两个成员, eth1 的错误逐渐增加后恢复, eth0 一直正常
+	counters := func(eth1Errs, eth1Drop int64) map[string]TsNetDev {
+		return map[string]TsNetDev{
+			"eth0": {Name: "eth0", Receive: TsNetDevInfo{Errs: 1}},
+			"eth1": {Name: "eth1", Receive: TsNetDevInfo{Errs: eth1Errs}, Transmit: TsNetDevInfo{Drop: eth1Drop}},
+		}
+	}
+	steps := []map[string]TsNetDev{
+		counters(0, 0),
+		counters(4, 0),   // 4/s, 未超过下限
+		counters(14, 0),  // 10/s
+		counters(34, 0),  // 20/s, 仍在突增中, 不重复回调
+		counters(34, 0),  // 恢复
+		counters(34, 12), // 丢包 12/s
+	}
+	for i := 1; i < len(steps); i++ {
+		n.sample(steps[i-1], steps[i], sampleTiming{elapsed: time.Second})
+	}
+	if want := []string{"eth1 10 0", "eth1 0 12"}; !slices.Equal(events, want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+}
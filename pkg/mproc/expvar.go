@@ -0,0 +1,62 @@
+package mproc
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// expvarSink 将最近一次采样的速率和累计字节数发布为 expvar 变量
+type expvarSink struct {
+	rateRx, rateTx   expvar.Float // 最近一次采样的速率, 字节/秒
+	totalRx, totalTx expvar.Int   // 监控启动以来各采样增量之和
+	samples          expvar.Int
+	sampledAt        expvar.String
+}
+
+// newExpvarSink 在 prefix 下发布变量; prefix 已发布为 expvar.Map 时 (例如重建监控) 覆盖其中的同名变量,
+// 已被其他类型的变量占用时只记录错误, 不会像 expvar.Publish 那样 panic
+func newExpvarSink(prefix string) *expvarSink {
+	s := &expvarSink{}
+	m, ok := expvar.Get(prefix).(*expvar.Map)
+	if !ok {
+		if expvar.Get(prefix) != nil {
+			mlog.Error(mlog.H{"msg": "expvar name already in use", "name": prefix})
+			return s
+		}
+		m = expvar.NewMap(prefix)
+	}
+	m.Set("bytes_rx", &s.rateRx)
+	m.Set("bytes_tx", &s.rateTx)
+	m.Set("total_rx", &s.totalRx)
+	m.Set("total_tx", &s.totalTx)
+	m.Set("samples", &s.samples)
+	m.Set("sampled_at", &s.sampledAt)
+	return s
+}
+
+// WithExpvar 将最近一次采样的收发速率 (bytes_rx, bytes_tx) 和监控启动以来的累计字节数 (total_rx, total_tx)
+// 发布为 expvar 中名为 prefix 的 Map, 通过标准库的 /debug/vars 查看, 每个采样更新一次
+func WithExpvar(prefix string) netDevOpts {
+	return func(t *netDev) {
+		WithSink(newExpvarSink(prefix))(t)
+	}
+}
+
+func (s *expvarSink) Write(data TsCallData) error {
+	s.rateRx.Set(data.BytesRxF)
+	s.rateTx.Set(data.BytesTxF)
+	s.totalRx.Add(data.DeltaRx)
+	s.totalTx.Add(data.DeltaTx)
+	s.samples.Add(1)
+	if !data.SampledAt.IsZero() {
+		s.sampledAt.Set(data.SampledAt.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// Close 保留已发布的变量, expvar 不支持取消发布
+func (s *expvarSink) Close() error {
+	return nil
+}
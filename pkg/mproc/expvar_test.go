@@ -0,0 +1,47 @@
+package mproc
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestWithExpvar(t *testing.T) {
+	n := newFIFONetDev(t, 50*time.Millisecond, WithExpvar("mproc_test"))
+	n.feed(netDevFile("eth0", 0, 0))
+	var sent [2]TsCallData
+	for i, rx := range []int{1000, 3000} {
+		n.feed(netDevFile("eth0", rx, rx/2))
+		sent[i] = n.next()
+	}
+	n.Close()
+
+	var vars struct {
+		BytesRx   float64 `json:"bytes_rx"`
+		BytesTx   float64 `json:"bytes_tx"`
+		TotalRx   int64   `json:"total_rx"`
+		TotalTx   int64   `json:"total_tx"`
+		Samples   int64   `json:"samples"`
+		SampledAt string  `json:"sampled_at"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("mproc_test").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	last := sent[1]
+	if vars.BytesRx != last.BytesRxF || vars.BytesTx != last.BytesTxF {
+		t.Errorf("rates = %v/%v, want %v/%v", vars.BytesRx, vars.BytesTx, last.BytesRxF, last.BytesTxF)
+	}
+	if vars.TotalRx != 3000 || vars.TotalTx != 1500 || vars.Samples != 2 {
+		t.Errorf("totals = %d/%d over %d samples, want 3000/1500 over 2", vars.TotalRx, vars.TotalTx, vars.Samples)
+	}
+	if vars.SampledAt != last.SampledAt.Format(time.RFC3339Nano) {
+		t.Errorf("sampled_at = %q, want %v", vars.SampledAt, last.SampledAt)
+	}
+
+	// 同名重建监控时不会 panic, 变量改为指向新的监控
+	newExpvarSink("mproc_test").Write(TsCallData{DeltaRx: 7})
+	if got := expvar.Get("mproc_test").(*expvar.Map).Get("total_rx").String(); got != "7" {
+		t.Errorf("total_rx after re-registering = %s, want 7", got)
+	}
+}
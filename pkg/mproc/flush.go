@@ -0,0 +1,21 @@
+package mproc
+
+// flushResult Flush 的结果, 由采样协程返回
+type flushResult struct {
+	data TsCallData
+	ok   bool
+}
+
+// Flush 立即读取一次并计算与上一次读取之间的速率, 与定时采样一样回调并写入输出, 返回该采样;
+// 本次读取成为新的基线, 下一次定时采样按实际经过的时间计算. 还没有基线、读取失败或监控已关闭时
+// 返回 false. 在采样协程中执行, 不能在回调中调用
+func (t *netDev) Flush() (TsCallData, bool) {
+	reply := make(chan flushResult, 1)
+	select {
+	case t.flushes <- reply:
+	case <-t.done:
+		return TsCallData{}, false
+	}
+	r := <-reply
+	return r.data, r.ok
+}
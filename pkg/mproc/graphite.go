@@ -0,0 +1,100 @@
+package mproc
+
+import (
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+const (
+	graphiteQueueSize    = 1024        // 待发送采样的最大数量, 超出时丢弃新采样
+	graphiteRedial       = time.Second // 连接失败后重新连接的最短间隔, 其间的采样被丢弃
+	graphiteDialTimeout  = time.Second // 单次连接的最长等待
+	graphiteWriteTimeout = time.Second // 单次发送的最长等待
+)
+
+// graphiteWriter 将每个采样以 Graphite plaintext 协议通过 TCP 发送, 连接在采样之间复用
+type graphiteWriter struct {
+	*queueSink
+	addr   string
+	prefix string
+	redial time.Duration
+}
+
+func newGraphiteWriter(addr, prefix string) *graphiteWriter {
+	w := &graphiteWriter{
+		addr:   addr,
+		prefix: strings.TrimSuffix(prefix, "."),
+		redial: graphiteRedial,
+	}
+	w.queueSink = newQueueSink("graphite", graphiteQueueSize, w.run)
+	return w
+}
+
+// WithGraphite 每个采样向 addr (Carbon 的 plaintext 端口, 通常为 2003) 发送
+// "prefix.bytes_rx <速率> <秒级时间戳>" 形式的汇总行, 以及每个接口的 "prefix.<接口>.bytes_rx" 等,
+// 接口名中 Graphite 路径不允许的字符 (包括 VLAN 接口名中的 .) 替换为 _.
+// 发送在单独的协程中进行, 不会阻塞采样协程; 连接失败时丢弃采样并定期重新连接
+func WithGraphite(addr, prefix string) netDevOpts {
+	return func(t *netDev) {
+		WithSink(newGraphiteWriter(addr, prefix))(t)
+	}
+}
+
+func (w *graphiteWriter) run(queue <-chan queuedSample) {
+	conn := &redialConn{
+		dial:    func() (net.Conn, error) { return net.DialTimeout("tcp", w.addr, graphiteDialTimeout) },
+		redial:  w.redial,
+		timeout: graphiteWriteTimeout,
+		target:  mlog.H{"addr": w.addr},
+	}
+	defer conn.close()
+	for s := range queue {
+		conn.send(w.lines(s.data))
+	}
+}
+
+// lines 将采样编码为 plaintext 协议的行, 时间戳为采样时间
+func (w *graphiteWriter) lines(data TsCallData) []byte {
+	at := data.SampledAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	epoch := strconv.FormatInt(at.Unix(), 10)
+	var b []byte
+	add := func(path string, value float64) {
+		if w.prefix != "" {
+			path = w.prefix + "." + path
+		}
+		b = append(b, path...)
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, value, 'f', -1, 64)
+		b = append(b, ' ')
+		b = append(b, epoch...)
+		b = append(b, '\n')
+	}
+	add("bytes_rx", data.BytesRxF)
+	add("bytes_tx", data.BytesTxF)
+	for _, name := range slices.Sorted(maps.Keys(data.PerInterface)) {
+		r := data.PerInterface[name]
+		iface := graphiteName(name)
+		add(iface+".bytes_rx", r.BytesRxF)
+		add(iface+".bytes_tx", r.BytesTxF)
+	}
+	return b
+}
+
+// graphiteName 将 Graphite 路径中不允许的字符替换为 _
+func graphiteName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
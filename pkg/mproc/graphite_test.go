@@ -0,0 +1,90 @@
+package mproc
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithGraphite(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	lines := make(chan string, 64)
+	accepted := make(chan struct{}, 4)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer conn.Close()
+				s := bufio.NewScanner(conn)
+				for s.Scan() {
+					lines <- s.Text()
+				}
+			}()
+		}
+	}()
+
+	at := time.Unix(1700000000, 0)
+	w := newGraphiteWriter(l.Addr().String(), "hosts.node1.")
+	for range 2 {
+		w.Write(TsCallData{
+			BytesRxF: 1500, BytesTxF: 0.5, SampledAt: at,
+			PerInterface: map[string]TsInterfaceRate{"eth0.100": {BytesRxF: 1500, BytesTxF: 0.5}},
+		})
+	}
+	w.Close()
+
+	want := []string{
+		"hosts.node1.bytes_rx 1500 1700000000",
+		"hosts.node1.bytes_tx 0.5 1700000000",
+		"hosts.node1.eth0_100.bytes_rx 1500 1700000000",
+		"hosts.node1.eth0_100.bytes_tx 0.5 1700000000",
+	}
+	want = append(want, want...)
+	for i, line := range want {
+		select {
+		case got := <-lines:
+			if got != line {
+				t.Fatalf("line %d = %q, want %q", i, got, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("line %d not received", i)
+		}
+	}
+	if len(accepted) != 1 {
+		t.Fatalf("got %d connections, want the connection reused", len(accepted))
+	}
+}
+
+func TestGraphiteUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	addr := l.Addr().String()
+	l.Close() // 没有监听的端口, 连接被拒绝
+
+	w := newGraphiteWriter(addr, "")
+	start := time.Now()
+	for range graphiteQueueSize + 10 {
+		w.Write(TsCallData{BytesRxF: 1})
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("Write blocked without a server")
+	}
+	w.Close()
+
+	// 没有前缀时路径不以 . 开头
+	if got := string(w.lines(TsCallData{SampledAt: time.Unix(1, 0)})); !strings.HasPrefix(got, "bytes_rx 0 1\n") {
+		t.Fatalf("lines without prefix = %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package mproc
+
+import "time"
+
+// WithGroup 定义名为 name 的接口组, 例如所有 WAN 口或所有存储网卡; 回调数据的 Groups 中包含每个组的收发速率之和.
+// 一个接口可以属于多个组, 重复调用同一个 name 时替换该组的接口
+func WithGroup(name string, interfaces []string) netDevOpts {
+	return func(t *netDev) {
+		if t.args.Groups == nil {
+			t.args.Groups = make(map[string][]string)
+		}
+		t.args.Groups[name] = interfaces
+	}
+}
+
+// groupRates 按计数器计算每个组的速率, 不依赖 MetricPerInterface; 组内没有读取到的接口不计入.
+// 与 PerInterface 一致, 速率按 base 到 cur 经过 span 计算 (WithRateBaseline), Delta 仍是 prev 到 cur 的增量
+func (n *netDev) groupRates(data *TsCallData, prev, base, cur map[string]TsNetDev, span time.Duration) {
+	if len(n.args.Groups) == 0 {
+		return
+	}
+	var sat saturation
+	data.Groups = make(map[string]TsInterfaceRate, len(n.args.Groups))
+	for group, ifaces := range n.args.Groups {
+		var rate TsInterfaceRate
+		rate.DeltaRx, rate.DeltaTx = groupDelta(&sat, ifaces, prev, cur)
+		wideRx, wideTx := groupDelta(&sat, ifaces, base, cur)
+		rate.BytesRxF, rate.BytesTxF = perSecond(wideRx, span), perSecond(wideTx, span)
+		rate.BytesRx, rate.BytesTx = sat.toInt(rate.BytesRxF), sat.toInt(rate.BytesTxF)
+		data.Groups[group] = rate
+	}
+	data.Saturated = data.Saturated || sat.saturated
+}
+
+// groupDelta 返回组内接口从 prev 到 cur 的收发增量之和, 只计入两次都读取到的接口
+func groupDelta(sat *saturation, ifaces []string, prev, cur map[string]TsNetDev) (rx, tx int64) {
+	for _, name := range ifaces {
+		p, inPrev := prev[name]
+		c, inCur := cur[name]
+		if !inPrev || !inCur {
+			continue
+		}
+		rx = sat.add(rx, counterDelta(p.Receive.Bytes, c.Receive.Bytes))
+		tx = sat.add(tx, counterDelta(p.Transmit.Bytes, c.Transmit.Bytes))
+	}
+	return rx, tx
+}
@@ -0,0 +1,58 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithGroup(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	// eth1 同时属于两个组, eth9 没有读取到
+	WithGroup("wan", []string{"eth0", "eth1"})(n)
+	WithGroup("storage", []string{"eth1", "eth2", "eth9"})(n)
+
+	prev := snapshot("eth0", 0, 0, "eth1", 0, 0, "eth2", 0, 0, "eth3", 0, 0)
+	cur := snapshot("eth0", 1000, 100, "eth1", 2000, 200, "eth2", 4000, 400, "eth3", 8000, 800)
+	data := n.sample(prev, cur, sampleTiming{elapsed: 2 * time.Second})
+
+	if len(data.Groups) != 2 {
+		t.Fatalf("Groups = %+v, want wan and storage", data.Groups)
+	}
+	if wan := data.Groups["wan"]; wan.BytesRx != 1500 || wan.BytesTx != 150 || wan.DeltaRx != 3000 {
+		t.Fatalf("wan = %+v, want 1500/150 over 2s", wan)
+	}
+	if storage := data.Groups["storage"]; storage.BytesRx != 3000 || storage.BytesTx != 300 || storage.BytesRxF != 3000 {
+		t.Fatalf("storage = %+v, want 3000/300", storage)
+	}
+	if data.BytesRx != 7500 || data.PerInterface["eth1"].BytesRx != 1000 {
+		t.Fatalf("total %d and eth1 %+v should not be affected by groups", data.BytesRx, data.PerInterface["eth1"])
+	}
+
+	// 没有定义组时不填充
+	n = &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	if data := n.sample(prev, cur, sampleTiming{elapsed: time.Second}); data.Groups != nil {
+		t.Fatalf("Groups = %+v without WithGroup, want nil", data.Groups)
+	}
+}
+
+// WithRateBaseline 时组的速率与其接口的速率使用同一基线, 增量仍是最近一个间隔的
+func TestWithGroupRateBaseline(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	WithGroup("wan", []string{"eth0", "eth1"})(n)
+	WithRateBaseline(2)(n)
+	counters := []int{0, 100, 300, 600}
+	var data TsCallData
+	for i := range 3 {
+		prev := snapshot("eth0", counters[i], 0, "eth1", 2*counters[i], 0)
+		cur := snapshot("eth0", counters[i+1], 0, "eth1", 2*counters[i+1], 0)
+		data = n.sample(prev, cur, sampleTiming{elapsed: time.Second})
+	}
+	// 最近两个间隔: eth0 (600-100)/2s, eth1 (1200-200)/2s
+	wan := data.Groups["wan"]
+	if sum := data.PerInterface["eth0"].BytesRx + data.PerInterface["eth1"].BytesRx; wan.BytesRx != 750 || sum != 750 {
+		t.Fatalf("wan = %+v, interfaces sum %d; want 750", wan, sum)
+	}
+	if wan.DeltaRx != 900 {
+		t.Fatalf("wan DeltaRx = %d, want 900 from the last interval", wan.DeltaRx)
+	}
+}
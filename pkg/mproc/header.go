@@ -0,0 +1,123 @@
+package mproc
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+)
+
+// netDevColumns 由表头确定的列顺序, 每一列对应一个计数器
+type netDevColumns []func(*TsNetDev) *int64
+
+// netDevCounters 表头中的列名对应的计数器, 接收和发送部分分别查找
+var netDevCounters = map[string]func(*TsNetDevInfo) *int64{
+	"bytes":      func(i *TsNetDevInfo) *int64 { return &i.Bytes },
+	"packets":    func(i *TsNetDevInfo) *int64 { return &i.Packets },
+	"errs":       func(i *TsNetDevInfo) *int64 { return &i.Errs },
+	"drop":       func(i *TsNetDevInfo) *int64 { return &i.Drop },
+	"fifo":       func(i *TsNetDevInfo) *int64 { return &i.FIFO },
+	"frame":      func(i *TsNetDevInfo) *int64 { return &i.Frame },
+	"compressed": func(i *TsNetDevInfo) *int64 { return &i.Compressed },
+	"multicast":  func(i *TsNetDevInfo) *int64 { return &i.Multicast },
+	"colls":      func(i *TsNetDevInfo) *int64 { return &i.Colls },
+	"carrier":    func(i *TsNetDevInfo) *int64 { return &i.Carrier },
+}
+
+// parseNetDevColumns 解析第二行表头 " face |bytes packets ...|bytes packets ...",
+// 不认识的列名保留位置但忽略其值; 不是列名表头时返回 false
+func parseNetDevColumns(line string) (netDevColumns, bool) {
+	parts := strings.Split(line, "|")
+	if len(parts) != 3 || strings.TrimSpace(parts[0]) != "face" {
+		return nil, false
+	}
+	var columns netDevColumns
+	for i, section := range parts[1:] {
+		for _, name := range strings.Fields(section) {
+			counter, ok := netDevCounters[strings.ToLower(name)]
+			if !ok {
+				columns = append(columns, nil)
+				continue
+			}
+			transmit := i == 1
+			columns = append(columns, func(d *TsNetDev) *int64 {
+				if transmit {
+					return counter(&d.Transmit)
+				}
+				return counter(&d.Receive)
+			})
+		}
+	}
+	return columns, len(columns) > 0
+}
+
+// parse 按列名解析一行接口数据, 字段数少于列数时返回 false
+func (c netDevColumns) parse(line string) (TsNetDev, bool) {
+	name, rest, _ := strings.Cut(line, ":")
+	fields := strings.Fields(rest)
+	if len(fields) < len(c) {
+		return TsNetDev{}, false
+	}
+	iface := TsNetDev{Name: strings.TrimSpace(name)}
+	for i, counter := range c {
+		if counter != nil {
+			*counter(&iface) = toCounter(fields[i])
+		}
+	}
+	return iface, true
+}
+
+// netDevColumnNames 返回第二行表头中的列名, 接收部分加 rx_ 前缀, 发送部分加 tx_ 前缀, 例如 rx_bytes;
+// 不是列名表头时返回 false
+func netDevColumnNames(line string) ([]string, bool) {
+	parts := strings.Split(line, "|")
+	if len(parts) != 3 || strings.TrimSpace(parts[0]) != "face" {
+		return nil, false
+	}
+	var names []string
+	for i, section := range parts[1:] {
+		prefix := [2]string{"rx_", "tx_"}[i]
+		for _, name := range strings.Fields(section) {
+			names = append(names, prefix+strings.ToLower(name))
+		}
+	}
+	return names, len(names) > 0
+}
+
+// Columns 读取一次网络设备文件 (WithPaths 时为第一个文件), 返回表头中的列顺序, 例如
+// rx_bytes, rx_packets, ..., tx_compressed, 用于确认按位置解析时与内核的格式一致.
+// 使用 StatsSource、读取失败、没有列名表头或监控已关闭时返回 nil; 不要用于只能读取一次的文件 (例如命名管道).
+// 在采样协程中执行, 不能在回调中调用
+func (t *netDev) Columns() []string {
+	result := make(chan []string, 1)
+	read := func() {
+		result <- t.columns()
+	}
+	select {
+	case t.reconfig <- read:
+		return <-result
+	case <-t.done:
+		return nil
+	}
+}
+
+func (t *netDev) columns() []string {
+	if t.source != nil || t.replaying {
+		return nil
+	}
+	path := t.args.Path
+	if len(t.args.Paths) > 0 {
+		path = t.args.Paths[0]
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if names, ok := netDevColumnNames(scanner.Text()); ok {
+			return names
+		}
+	}
+	return nil
+}
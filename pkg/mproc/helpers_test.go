@@ -0,0 +1,126 @@
+package mproc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fixtureNetDev 来自真实内核的 /proc/net/dev 内容
+const fixtureNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 2776770   11307    0    0    0     0          0         0  2776770   11307    0    0    0     0       0          0
+  eth0: 1215645   2751    0    0    0     0          0         0  1782404   4324    0    0    0   427       0          0
+`
+
+// netDevFile 生成 /proc/net/dev 格式的内容, 参数依次为接口名、接收字节数、发送字节数, 其他计数为 0
+func netDevFile(ifaces ...any) string {
+	var b strings.Builder
+	b.WriteString(fixtureNetDev[:strings.Index(fixtureNetDev, "\n    lo:")+1])
+	for i := 0; i+2 < len(ifaces); i += 3 {
+		fmt.Fprintf(&b, "%6s: %d 0 0 0 0 0 0 0 %d 0 0 0 0 0 0 0\n", ifaces[i], ifaces[i+1], ifaces[i+2])
+	}
+	return b.String()
+}
+
+// writeFile 写入文件, 按需创建目录
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fifoNetDev 从命名管道读取的监控: 每次读取都阻塞到 feed 写入一份内容,
+// 因此真实定时器下每次 feed 恰好对应一次采样, 样本从 samples 中取出
+type fifoNetDev struct {
+	*netDev
+	t       *testing.T
+	path    string
+	samples chan TsCallData
+}
+
+// newFIFONetDev 创建从命名管道读取的监控, 测试结束时关闭
+func newFIFONetDev(t *testing.T, interval time.Duration, opts ...netDevOpts) *fifoNetDev {
+	t.Helper()
+	f := &fifoNetDev{t: t, path: filepath.Join(t.TempDir(), "dev"), samples: make(chan TsCallData, 16)}
+	if err := syscall.Mkfifo(f.path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	base := []netDevOpts{WithPath(f.path), WithCallback(func(data TsCallData) {
+		select {
+		case f.samples <- data:
+		default:
+		}
+	})}
+	n, err := NewNetDev("test", interval, append(base, opts...)...)
+	if err != nil {
+		t.Fatalf("NewNetDev: %v", err)
+	}
+	f.netDev = n
+	t.Cleanup(f.Close)
+	return f
+}
+
+// feed 写入下一次读取的内容
+func (f *fifoNetDev) feed(content string) {
+	f.t.Helper()
+	w, err := os.OpenFile(f.path, os.O_WRONLY, 0)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if _, err := w.WriteString(content); err != nil {
+		f.t.Fatal(err)
+	}
+	w.Close()
+	// 等待读取方关闭管道, 否则下一次 feed 会写进同一次读取; 下一次读取要到下一个周期才开始
+	for f.unblock() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// unblock 打开再关闭写端, 返回是否有读取方, 正在等待的读取会得到空内容
+func (f *fifoNetDev) unblock() bool {
+	w, err := os.OpenFile(f.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return false
+	}
+	w.Close()
+	return true
+}
+
+// next 等待下一个样本
+func (f *fifoNetDev) next() TsCallData {
+	f.t.Helper()
+	select {
+	case data := <-f.samples:
+		return data
+	case <-time.After(10 * time.Second):
+		f.t.Fatal("no sample within 10s")
+		return TsCallData{}
+	}
+}
+
+// Close 关闭监控, 采样协程可能正阻塞在打开管道上, 需要反复解除
+func (f *fifoNetDev) Close() {
+	closed := make(chan struct{})
+	go func() {
+		f.netDev.Close()
+		close(closed)
+	}()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-time.After(time.Millisecond):
+			f.unblock()
+		}
+	}
+}
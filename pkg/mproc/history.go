@@ -0,0 +1,186 @@
+package mproc
+
+import (
+	"sync"
+	"time"
+)
+
+// 历史数据的分层保留策略, 类似 RRD:
+// 最近 1 分钟保留原始采样, 最近 1 小时按 10 秒聚合, 更早的按 1 分钟聚合
+const (
+	historyRawSpan  = time.Minute
+	historyMidSpan  = time.Hour
+	historyMidStep  = 10 * time.Second
+	historyLongStep = time.Minute
+)
+
+// TsHistoryPoint 历史数据中的一个点, 聚合点的速率为区间内的平均值
+type TsHistoryPoint struct {
+	Time    time.Time // 区间起始时间, 原始采样为采样时间
+	BytesRx int64     // 平均接收速率
+	BytesTx int64     // 平均发送速率
+	DeltaRx int64     // 区间内接收的总字节数
+	DeltaTx int64     // 区间内发送的总字节数
+	Samples int       // 区间内的采样数
+}
+
+type historyBucket struct {
+	start            time.Time
+	sumRx, sumTx     int64 // 速率之和, 用于计算平均值
+	deltaRx, deltaTx int64
+	samples          int
+}
+
+func (b *historyBucket) add(o historyBucket) {
+	b.sumRx += o.sumRx
+	b.sumTx += o.sumTx
+	b.deltaRx += o.deltaRx
+	b.deltaTx += o.deltaTx
+	b.samples += o.samples
+}
+
+func (b historyBucket) point() TsHistoryPoint {
+	p := TsHistoryPoint{
+		Time:    b.start,
+		DeltaRx: b.deltaRx,
+		DeltaTx: b.deltaTx,
+		Samples: b.samples,
+	}
+	if b.samples > 0 {
+		p.BytesRx = b.sumRx / int64(b.samples)
+		p.BytesTx = b.sumTx / int64(b.samples)
+	}
+	return p
+}
+
+type history struct {
+	mu        sync.Mutex
+	retention time.Duration   // 最长保留时长
+	raw       []historyBucket // 原始采样
+	mid       []historyBucket // 10 秒聚合
+	long      []historyBucket // 1 分钟聚合
+	since     time.Time       // 保留的数据覆盖的最早时间
+}
+
+func newHistory(retention time.Duration) *history {
+	return &history{retention: retention}
+}
+
+// add 记录一次采样, 并将过期的数据降采样到下一层
+func (h *history) add(at time.Time, data TsCallData, deltaRx, deltaTx int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.since.IsZero() {
+		// 第一次采样的增量从上一次读取开始
+		h.since = data.PrevSampledAt
+		if h.since.IsZero() {
+			h.since = at
+		}
+	}
+	h.raw = append(h.raw, historyBucket{
+		start:   at,
+		sumRx:   data.BytesRx,
+		sumTx:   data.BytesTx,
+		deltaRx: deltaRx,
+		deltaTx: deltaTx,
+		samples: 1,
+	})
+
+	var expired []historyBucket
+	h.raw, expired = splitBefore(h.raw, at.Add(-historyRawSpan))
+	h.mid = mergeInto(h.mid, expired, historyMidStep)
+
+	h.mid, expired = splitBefore(h.mid, at.Add(-historyMidSpan))
+	h.long = mergeInto(h.long, expired, historyLongStep)
+
+	if h.retention > 0 {
+		cutoff := at.Add(-h.retention)
+		trimmed := false
+		for _, tier := range []*[]historyBucket{&h.raw, &h.mid, &h.long} {
+			*tier, expired = splitBefore(*tier, cutoff)
+			trimmed = trimmed || len(expired) > 0
+		}
+		if trimmed {
+			for _, tier := range [][]historyBucket{h.long, h.mid, h.raw} {
+				if len(tier) > 0 {
+					h.since = tier[0].start
+					break
+				}
+			}
+		}
+	}
+}
+
+// bytesSince 返回 t 之后传输的总字节数, 原始采样按采样时间计入, 与 t 相交的聚合区间整体计入.
+// t 早于保留的数据覆盖的最早时间时 ok 为 false
+func (h *history) bytesSince(t time.Time) (rx, tx int64, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.since.IsZero() || t.Before(h.since) {
+		return 0, 0, false
+	}
+	tiers := []struct {
+		buckets []historyBucket
+		step    time.Duration
+	}{{h.long, historyLongStep}, {h.mid, historyMidStep}, {h.raw, 0}}
+	for _, tier := range tiers {
+		for _, b := range tier.buckets {
+			if b.start.Add(tier.step).After(t) {
+				rx += b.deltaRx
+				tx += b.deltaTx
+			}
+		}
+	}
+	return rx, tx, true
+}
+
+// points 返回按 resolution 降采样后的历史数据, 按时间升序
+//
+// 较早的数据只保留了较粗的粒度, 无法还原为比其所在层更细的粒度
+func (h *history) points(resolution time.Duration) []TsHistoryPoint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	all := make([]historyBucket, 0, len(h.long)+len(h.mid)+len(h.raw))
+	all = append(all, h.long...)
+	all = append(all, h.mid...)
+	all = append(all, h.raw...)
+	if resolution > 0 {
+		all = mergeInto(nil, all, resolution)
+	}
+
+	result := make([]TsHistoryPoint, 0, len(all))
+	for _, b := range all {
+		result = append(result, b.point())
+	}
+	return result
+}
+
+// splitBefore 将早于 cutoff 的数据拆分出来, buckets 需按时间升序
+func splitBefore(buckets []historyBucket, cutoff time.Time) (kept, expired []historyBucket) {
+	i := 0
+	for i < len(buckets) && buckets[i].start.Before(cutoff) {
+		i++
+	}
+	if i == 0 {
+		return buckets, nil
+	}
+	expired = append([]historyBucket(nil), buckets[:i]...)
+	return buckets[i:], expired
+}
+
+// mergeInto 将 src 按 step 对齐聚合后追加到 dst, 两者均需按时间升序
+func mergeInto(dst, src []historyBucket, step time.Duration) []historyBucket {
+	for _, b := range src {
+		start := b.start.Truncate(step)
+		if n := len(dst); n > 0 && dst[n-1].start.Equal(start) {
+			dst[n-1].add(b)
+			continue
+		}
+		b.start = start
+		dst = append(dst, b)
+	}
+	return dst
+}
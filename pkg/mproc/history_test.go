@@ -0,0 +1,136 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryDownsampling(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory(0)
+	for i := range 120 {
+		h.add(t0.Add(time.Duration(i)*time.Second), TsCallData{BytesRx: int64(i), BytesTx: 1}, int64(i), 1)
+	}
+
+	// 超过 1 分钟的采样按 10 秒聚合, 平均值与原始采样的平均值一致
+	points := h.points(0)
+	if len(points) != 6+61 {
+		t.Fatalf("got %d points, want 6 aggregated + 61 raw", len(points))
+	}
+	if p := points[0]; !p.Time.Equal(t0) || p.Samples != 10 || p.BytesRx != 4 || p.DeltaRx != 45 {
+		t.Fatalf("first 10s bucket = %+v, want avg 4 over 10 samples", p)
+	}
+	if p := points[5]; p.Samples != 9 || p.BytesRx != (50+58)*9/2/9 {
+		t.Fatalf("partial 10s bucket = %+v", p)
+	}
+	if p := points[6]; !p.Time.Equal(t0.Add(59*time.Second)) || p.Samples != 1 || p.BytesRx != 59 {
+		t.Fatalf("first raw point = %+v, want the sample at 59s", p)
+	}
+
+	minutes := h.points(time.Minute)
+	if len(minutes) != 2 {
+		t.Fatalf("got %d minute points, want 2", len(minutes))
+	}
+	for i, want := range []int64{(0 + 59) * 60 / 2 / 60, (60 + 119) * 60 / 2 / 60} {
+		p := minutes[i]
+		if p.Samples != 60 || p.BytesRx != want || p.BytesTx != 1 || p.DeltaTx != 60 {
+			t.Fatalf("minute %d = %+v, want avg %d over 60 samples", i, p, want)
+		}
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory(90 * time.Minute)
+	last := t0
+	for i := range 3 * 360 { // 3 小时, 每 10 秒一个采样
+		last = t0.Add(time.Duration(i) * 10 * time.Second)
+		h.add(last, TsCallData{BytesRx: 1}, 10, 0)
+	}
+	points := h.points(0)
+	if oldest := points[0].Time; oldest.Before(last.Add(-90 * time.Minute)) {
+		t.Fatalf("oldest point %v is older than the retention", oldest)
+	}
+	for i := 1; i < len(points); i++ {
+		if !points[i].Time.After(points[i-1].Time) {
+			t.Fatalf("points not in ascending order at %d", i)
+		}
+	}
+}
+
+// 保留时长对每一层都生效, 短于原始层和 10 秒层的跨度时同样适用
+func TestHistoryShortRetention(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory(5 * time.Minute)
+	last := t0
+	for i := range 3600 { // 1 小时, 每秒一个采样
+		last = t0.Add(time.Duration(i) * time.Second)
+		h.add(last, TsCallData{}, 1, 0)
+	}
+	cutoff := last.Add(-5 * time.Minute)
+	for _, p := range h.points(0) {
+		if p.Time.Before(cutoff) {
+			t.Fatalf("point at %v is older than the 5m retention (cutoff %v)", p.Time, cutoff)
+		}
+	}
+	if len(h.long) != 0 || len(h.mid) == 0 {
+		t.Fatalf("tiers: %d mid, %d long buckets; want only recent mid buckets", len(h.mid), len(h.long))
+	}
+}
+
+func TestWithHistory(t *testing.T) {
+	n := newFIFONetDev(t, time.Second, WithHistory(time.Hour))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	n.next()
+	n.feed(netDevFile("eth0", 3000, 0))
+	n.next()
+	n.feed(netDevFile("eth0", 3000, 0)) // 下一次读取在上一个采样写入历史之后
+
+	points := n.History(0)
+	if len(points) < 2 || points[0].BytesRx != 1000 || points[1].BytesRx != 2000 {
+		t.Fatalf("History = %+v, want the two raw samples", points)
+	}
+	if points[1].DeltaRx != 2000 {
+		t.Fatalf("DeltaRx = %d, want 2000", points[1].DeltaRx)
+	}
+
+	if rx, _, ok := n.BytesSince(points[0].Time); !ok || rx != 2000 {
+		t.Fatalf("BytesSince(first sample) = %d, %v; want 2000, true", rx, ok)
+	}
+	if _, _, ok := n.BytesSince(points[0].Time.Add(-time.Hour)); ok {
+		t.Fatal("BytesSince before the history = ok, want !ok")
+	}
+}
+
+func TestHistoryBytesSince(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory(10 * time.Minute)
+	if _, _, ok := h.bytesSince(t0); ok {
+		t.Fatal("bytesSince on empty history = ok, want !ok")
+	}
+	for i := 1; i <= 20*60; i++ { // 20 分钟, 每秒一个采样, 每次 100 字节
+		at := t0.Add(time.Duration(i) * time.Second)
+		h.add(at, TsCallData{PrevSampledAt: at.Add(-time.Second)}, 100, 1)
+	}
+	end := t0.Add(20 * time.Minute)
+
+	cases := []struct {
+		since  time.Time
+		rx, tx int64
+		ok     bool
+	}{
+		{end, 0, 0, true},
+		{end.Add(-30 * time.Second), 3000, 30, true},                // 原始采样
+		{end.Add(-5 * time.Minute), 30100, 301, true},               // 10 秒聚合, 起点为 since 的区间整体计入
+		{end.Add(-5*time.Minute - 5*time.Second), 31100, 311, true}, // 与 since 相交的聚合区间整体计入
+		{end.Add(-10 * time.Minute), 60100, 601, true},              // 保留的最早时间
+		{end.Add(-11 * time.Minute), 0, 0, false},                   // 已经超出保留时长
+	}
+	for _, c := range cases {
+		rx, tx, ok := h.bytesSince(c.since)
+		if rx != c.rx || tx != c.tx || ok != c.ok {
+			t.Errorf("bytesSince(end-%v) = %d, %d, %v; want %d, %d, %v", end.Sub(c.since), rx, tx, ok, c.rx, c.tx, c.ok)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package mproc
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// detailRefresh 重新读取接口属性的间隔, MTU 和队列数很少变化, 不需要每次采样读取
+const detailRefresh = time.Minute
+
+// ifaceDetail 从 sysfs 读取的接口属性
+type ifaceDetail struct {
+	mtu      int
+	txQueues int
+	rxQueues int
+	readAt   time.Time
+}
+
+// detailTracker 为接口附加 MTU 和队列数, 每个接口每 detailRefresh 读取一次, 接口消失后重新读取
+type detailTracker struct {
+	root  string // sysfs 挂载点
+	cache map[string]ifaceDetail
+}
+
+func newDetailTracker(root string) *detailTracker {
+	return &detailTracker{root: root, cache: make(map[string]ifaceDetail)}
+}
+
+// WithInterfaceDetails 为 PerInterface 中的接口附加 sysfs 中的 MTU 和收发队列数, 每分钟重新读取一次;
+// sysfs 中没有该接口时为 0
+func WithInterfaceDetails(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.details = nil
+		if enabled {
+			t.details = &detailTracker{}
+		}
+	}
+}
+
+func (c *detailTracker) apply(data *TsCallData, at time.Time) {
+	for name := range c.cache {
+		if _, ok := data.PerInterface[name]; !ok {
+			delete(c.cache, name)
+		}
+	}
+	for name, rate := range data.PerInterface {
+		d, ok := c.cache[name]
+		if !ok || at.Sub(d.readAt) >= detailRefresh {
+			d = c.read(name)
+			d.readAt = at
+			c.cache[name] = d
+		}
+		rate.MTU, rate.TxQueues, rate.RxQueues = d.mtu, d.txQueues, d.rxQueues
+		data.PerInterface[name] = rate
+	}
+}
+
+// read 读取 /sys/class/net/<name>/mtu 和 queues 下的 tx-N、rx-N 目录数
+func (c *detailTracker) read(name string) ifaceDetail {
+	dir := filepath.Join(c.root, "class", "net", name)
+	var d ifaceDetail
+	if b, err := os.ReadFile(filepath.Join(dir, "mtu")); err == nil {
+		d.mtu, _ = strconv.Atoi(strings.TrimSpace(string(b)))
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "queues"))
+	for _, e := range entries {
+		switch {
+		case strings.HasPrefix(e.Name(), "tx-"):
+			d.txQueues++
+		case strings.HasPrefix(e.Name(), "rx-"):
+			d.rxQueues++
+		}
+	}
+	return d
+}
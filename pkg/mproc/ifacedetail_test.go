@@ -0,0 +1,50 @@
+package mproc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithInterfaceDetails(t *testing.T) {
+	root := t.TempDir()
+	net := filepath.Join(root, "class", "net")
+	writeFile(t, filepath.Join(net, "eth0", "mtu"), "9000\n")
+	for _, q := range []string{"tx-0", "tx-1", "tx-2", "tx-3", "rx-0", "rx-1"} {
+		if err := os.MkdirAll(filepath.Join(net, "eth0", "queues", q), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(net, "lo", "mtu"), "65536\n")
+
+	n, err := newNetDev("test", time.Second, WithSysfsRoot(root), WithInterfaceDetails(true), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := snapshot("eth0", 0, 0, "lo", 0, 0, "gone0", 0, 0)
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(at time.Time) TsCallData {
+		return n.sample(stats, stats, sampleTiming{elapsed: time.Second, at: at})
+	}
+
+	data := sample(t0)
+	if r := data.PerInterface["eth0"]; r.MTU != 9000 || r.TxQueues != 4 || r.RxQueues != 2 {
+		t.Fatalf("eth0 = %+v, want MTU 9000 with 4 tx and 2 rx queues", r)
+	}
+	if r := data.PerInterface["lo"]; r.MTU != 65536 || r.TxQueues != 0 {
+		t.Fatalf("lo = %+v, want MTU 65536 without queues", r)
+	}
+	if r := data.PerInterface["gone0"]; r.MTU != 0 {
+		t.Fatalf("gone0 = %+v, want zero details without sysfs", r)
+	}
+
+	// 刷新间隔内不重新读取
+	writeFile(t, filepath.Join(net, "eth0", "mtu"), "1500\n")
+	if r := sample(t0.Add(30 * time.Second)).PerInterface["eth0"]; r.MTU != 9000 {
+		t.Fatalf("MTU = %d within the refresh interval, want the cached 9000", r.MTU)
+	}
+	if r := sample(t0.Add(detailRefresh)).PerInterface["eth0"]; r.MTU != 1500 {
+		t.Fatalf("MTU = %d after the refresh interval, want 1500", r.MTU)
+	}
+}
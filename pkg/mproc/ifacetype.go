@@ -0,0 +1,84 @@
+package mproc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 接口类型, 见 WithInterfaceTypes
+const (
+	InterfaceEthernet = "ethernet" // 有物理设备的以太网接口
+	InterfaceWifi     = "wifi"     // 无线网卡
+	InterfaceLoopback = "loopback"
+	InterfaceVirtual  = "virtual" // 没有物理设备, 例如 veth、bridge、tun
+	InterfaceOther    = "other"   // 有物理设备的其他链路类型, 例如 InfiniBand
+)
+
+// ARPHRD_* 链路类型, 见 linux/if_arp.h
+const (
+	arphrdEther    = "1"
+	arphrdLoopback = "772"
+)
+
+// typeTracker 为接口标记类型, 每个接口只读取一次 sysfs, 接口消失后重新读取
+type typeTracker struct {
+	root  string // sysfs 挂载点
+	cache map[string]string
+}
+
+func newTypeTracker(root string) *typeTracker {
+	return &typeTracker{root: root, cache: make(map[string]string)}
+}
+
+// WithInterfaceTypes 根据 sysfs 中的 type、wireless 和 device 为 PerInterface 中的接口标记类型
+// (InterfaceEthernet、InterfaceWifi、InterfaceLoopback、InterfaceVirtual 或 InterfaceOther),
+// 便于在面板中分组. sysfs 中没有该接口时类型为空
+func WithInterfaceTypes(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.types = nil
+		if enabled {
+			t.types = &typeTracker{}
+		}
+	}
+}
+
+func (c *typeTracker) apply(data *TsCallData) {
+	for name := range c.cache {
+		if _, ok := data.PerInterface[name]; !ok {
+			delete(c.cache, name)
+		}
+	}
+	for name, rate := range data.PerInterface {
+		typ, ok := c.cache[name]
+		if !ok {
+			typ = c.classify(name)
+			c.cache[name] = typ
+		}
+		rate.Type = typ
+		data.PerInterface[name] = rate
+	}
+}
+
+// classify 读取 /sys/class/net/<name> 判断接口类型
+func (c *typeTracker) classify(name string) string {
+	dir := filepath.Join(c.root, "class", "net", name)
+	b, err := os.ReadFile(filepath.Join(dir, "type"))
+	if err != nil {
+		return ""
+	}
+	arphrd := strings.TrimSpace(string(b))
+	if arphrd == arphrdLoopback {
+		return InterfaceLoopback
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wireless")); err == nil {
+		return InterfaceWifi
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "device")); err != nil {
+		return InterfaceVirtual
+	}
+	if arphrd == arphrdEther {
+		return InterfaceEthernet
+	}
+	return InterfaceOther
+}
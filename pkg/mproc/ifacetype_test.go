@@ -0,0 +1,48 @@
+package mproc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInterfaceTypes(t *testing.T) {
+	root := t.TempDir()
+	net := filepath.Join(root, "class", "net")
+	devices := filepath.Join(root, "devices", "pci0000:00")
+	for name, typ := range map[string]string{"eth0": "1", "wlan0": "1", "lo": "772", "veth1": "1", "ib0": "32"} {
+		writeFile(t, filepath.Join(net, name, "type"), typ+"\n")
+	}
+	for _, name := range []string{"eth0", "wlan0", "ib0"} {
+		if err := os.MkdirAll(filepath.Join(devices, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(devices, name), filepath.Join(net, name, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(net, "wlan0", "wireless"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := newNetDev("test", time.Second, WithSysfsRoot(root), WithInterfaceTypes(true), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := snapshot("eth0", 0, 0, "wlan0", 0, 0, "lo", 0, 0, "veth1", 0, 0, "ib0", 0, 0, "gone0", 0, 0)
+	data := n.sample(stats, stats, sampleTiming{elapsed: time.Second})
+	want := map[string]string{
+		"eth0":  InterfaceEthernet,
+		"wlan0": InterfaceWifi,
+		"lo":    InterfaceLoopback,
+		"veth1": InterfaceVirtual,
+		"ib0":   InterfaceOther,
+		"gone0": "",
+	}
+	for name, typ := range want {
+		if got := data.PerInterface[name].Type; got != typ {
+			t.Errorf("%s type = %q, want %q", name, got, typ)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package mproc
+
+import "sync/atomic"
+
+// OverflowPolicy 同时执行的回调达到 WithMaxInflightCallbacks 的上限时新采样的处理方式
+type OverflowPolicy int
+
+const (
+	OverflowDrop OverflowPolicy = iota // 丢弃新采样并计数, 见 DroppedCallbacks
+	OverflowWait                       // 采样协程等待空闲的名额, 不丢弃采样, 但会推迟之后的读取
+)
+
+// inflightLimiter 每次回调在新协程中执行, 用信号量限制同时执行的数量
+type inflightLimiter struct {
+	sem      chan struct{}
+	policy   OverflowPolicy
+	callback func(data TsCallData)
+	dropped  atomic.Int64
+}
+
+// WithMaxInflightCallbacks 每次回调在新的协程中执行, 采样协程不会被耗时的回调阻塞,
+// 同时执行的回调最多 n 个 (小于 1 时按 1 处理), 超出时按 WithCallbackOverflow 处理, 默认丢弃.
+//
+// 回调之间不保证顺序, Close 不等待正在执行的回调. 设置 WithCallbackPool 时忽略
+func WithMaxInflightCallbacks(n int) netDevOpts {
+	return func(t *netDev) {
+		l := t.inflight.clone()
+		l.sem = make(chan struct{}, max(n, 1))
+		t.inflight = l
+	}
+}
+
+// WithCallbackOverflow 设置回调达到 WithMaxInflightCallbacks 上限时的处理方式, 需要同时使用 WithMaxInflightCallbacks
+func WithCallbackOverflow(policy OverflowPolicy) netDevOpts {
+	return func(t *netDev) {
+		l := t.inflight.clone()
+		l.policy = policy
+		t.inflight = l
+	}
+}
+
+// clone 复制配置, 选项总是替换而不是修改已有的 inflightLimiter, Reconfigure 可以发现修改
+func (l *inflightLimiter) clone() *inflightLimiter {
+	if l == nil {
+		return &inflightLimiter{}
+	}
+	return &inflightLimiter{sem: l.sem, policy: l.policy}
+}
+
+// dispatch 在新协程中执行回调, 没有空闲名额时按 policy 丢弃或等待
+func (l *inflightLimiter) dispatch(data TsCallData) {
+	select {
+	case l.sem <- struct{}{}:
+	default:
+		if l.policy == OverflowDrop {
+			l.dropped.Add(1)
+			return
+		}
+		l.sem <- struct{}{}
+	}
+	go func() {
+		defer func() { <-l.sem }()
+		l.callback(data)
+	}()
+}
+
+// DroppedCallbacks 返回因 WithMaxInflightCallbacks 的上限或 WithCallbackPool 的队列已满被丢弃的回调次数
+func (t *netDev) DroppedCallbacks() int64 {
+	if t.queue != nil {
+		return t.queue.dropped.Load()
+	}
+	if t.inflight == nil {
+		return 0
+	}
+	return t.inflight.dropped.Load()
+}
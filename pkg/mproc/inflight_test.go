@@ -0,0 +1,75 @@
+package mproc
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxInflightCallbacks(t *testing.T) {
+	const limit = 2
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	callback := func(TsCallData) {
+		cur := running.Add(1)
+		for p := peak.Load(); cur > p && !peak.CompareAndSwap(p, cur); p = peak.Load() {
+		}
+		<-release // 回调一直阻塞到测试结束
+		running.Add(-1)
+	}
+
+	base := runtime.NumGoroutine()
+	n, err := NewNetDev("slow", 10*time.Millisecond,
+		WithStatsSource(NewMockSource(snapshot("eth0", 0, 0), snapshot("eth0", 100, 0))),
+		WithCallback(callback),
+		WithMaxInflightCallbacks(limit),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	defer close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for n.DroppedCallbacks() < 10 {
+		if time.Now().After(deadline) {
+			t.Fatalf("dropped = %d, want samples dropped while callbacks block", n.DroppedCallbacks())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := peak.Load(); got != limit {
+		t.Fatalf("peak concurrent callbacks = %d, want %d", got, limit)
+	}
+	// 采样协程和 limit 个阻塞的回调
+	if got := runtime.NumGoroutine() - base; got > limit+1 {
+		t.Fatalf("goroutines grew by %d, want at most %d", got, limit+1)
+	}
+}
+
+func TestCallbackOverflowWait(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	n, err := NewNetDev("slow", 10*time.Millisecond,
+		WithStatsSource(NewMockSource(snapshot("eth0", 0, 0))),
+		WithCallback(func(TsCallData) {
+			calls.Add(1)
+			<-release
+		}),
+		WithMaxInflightCallbacks(1),
+		WithCallbackOverflow(OverflowWait),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	// 采样协程等待名额, 只有第一个回调在执行, 第二个采样在等待
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls = %d while blocked, want 1", got)
+	}
+	close(release)
+	n.Close()
+	if n.DroppedCallbacks() != 0 {
+		t.Fatalf("dropped = %d, want 0 with OverflowWait", n.DroppedCallbacks())
+	}
+}
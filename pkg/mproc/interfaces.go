@@ -0,0 +1,67 @@
+package mproc
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ifaceSet 最近一次读取到的接口, 采样协程写入, Interfaces 读取
+type ifaceSet struct {
+	mu    sync.Mutex
+	names []string // 已排序
+}
+
+// record 记录 stats 中的接口, 接口没有变化时不分配内存
+func (s *ifaceSet) record(stats map[string]TsNetDev) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.names != nil && len(s.names) == len(stats) {
+		same := true
+		for _, name := range s.names {
+			if _, ok := stats[name]; !ok {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+	s.names = slices.Sorted(maps.Keys(stats))
+}
+
+func (s *ifaceSet) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.names)
+}
+
+// Interfaces 返回按名称排序的正在监控的接口, 即最近一次读取中通过过滤规则的接口,
+// 与 WithInterfaces 配置的列表不同, 不包含不存在的接口. 还没有读取过时在采样协程中读取一次;
+// 读取失败或监控已关闭时返回 nil
+func (t *netDev) Interfaces() []string {
+	if names := t.seen.get(); names != nil {
+		return names
+	}
+	result := make(chan []string, 1)
+	read := func() {
+		if t.replaying {
+			result <- t.seen.get()
+			return
+		}
+		stats, _, err := t.readNetDev()
+		if err != nil {
+			result <- nil
+			return
+		}
+		t.seen.record(stats)
+		result <- t.seen.get()
+	}
+	select {
+	case t.reconfig <- read:
+		return <-result
+	case <-t.done:
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package mproc
+
+import (
+	"maps"
+	"time"
+)
+
+// WithInterpolation 在两次真实采样之间每隔 subInterval 插入一个线性插值的采样, 用于绘制平滑的曲线.
+//
+// 插值只是估计: 读取间隔内的真实变化无法测量. 插值点标记 Interpolated, 在下一次真实采样时、
+// 该次采样之前一并回调, 不写入输出和历史数据. subInterval 不小于采样间隔时不插值
+func WithInterpolation(subInterval time.Duration) netDevOpts {
+	return func(t *netDev) {
+		t.args.Interpolation = subInterval
+	}
+}
+
+// interpolate 回调上一次与本次真实采样之间的插值点, 并记录本次采样
+func (n *netDev) interpolate(data TsCallData) {
+	sub := n.args.Interpolation
+	if sub <= 0 {
+		n.last = nil
+		return
+	}
+	prev := n.last
+	n.last = &data
+	if prev == nil {
+		return
+	}
+	steps := int(data.Interval / sub)
+	for i := 1; i < steps; i++ {
+		n.args.Callback(interpolatePoint(*prev, data, float64(i)/float64(steps), sub))
+	}
+}
+
+// interpolatePoint 返回 prev 与 cur 之间比例 f 处的速率, 增量按 sub 内的速率估计
+func interpolatePoint(prev, cur TsCallData, f float64, sub time.Duration) TsCallData {
+	lerp := func(a, b float64) float64 { return a + (b-a)*f }
+	lerpInt := func(a, b int64) int64 { return int64(lerp(float64(a), float64(b))) }
+	p := TsCallData{
+		Name:         cur.Name,
+		Interval:     sub,
+		Interfaces:   cur.Interfaces,
+		Labels:       maps.Clone(cur.Labels),
+		BytesRxF:     lerp(prev.BytesRxF, cur.BytesRxF),
+		BytesTxF:     lerp(prev.BytesTxF, cur.BytesTxF),
+		AvgBytesRx:   lerp(prev.AvgBytesRx, cur.AvgBytesRx),
+		AvgBytesTx:   lerp(prev.AvgBytesTx, cur.AvgBytesTx),
+		PacketsRx:    lerpInt(prev.PacketsRx, cur.PacketsRx),
+		PacketsTx:    lerpInt(prev.PacketsTx, cur.PacketsTx),
+		ErrsRx:       lerpInt(prev.ErrsRx, cur.ErrsRx),
+		ErrsTx:       lerpInt(prev.ErrsTx, cur.ErrsTx),
+		DropRx:       lerpInt(prev.DropRx, cur.DropRx),
+		DropTx:       lerpInt(prev.DropTx, cur.DropTx),
+		NormalizedRx: lerp(prev.NormalizedRx, cur.NormalizedRx),
+		NormalizedTx: lerp(prev.NormalizedTx, cur.NormalizedTx),
+		SmoothedRx:   lerp(prev.SmoothedRx, cur.SmoothedRx),
+		SmoothedTx:   lerp(prev.SmoothedTx, cur.SmoothedTx),
+		Interpolated: true,
+	}
+	if !prev.SampledAt.IsZero() && !cur.SampledAt.IsZero() {
+		p.SampledAt = prev.SampledAt.Add(time.Duration(float64(cur.SampledAt.Sub(prev.SampledAt)) * f))
+		p.PrevSampledAt = p.SampledAt.Add(-sub)
+	}
+	p.BytesRx, p.BytesTx = int64(p.BytesRxF), int64(p.BytesTxF)
+	p.DeltaRx, p.DeltaTx = int64(p.BytesRxF*sub.Seconds()), int64(p.BytesTxF*sub.Seconds())
+	return p
+}
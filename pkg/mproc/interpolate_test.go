@@ -0,0 +1,34 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterpolation(t *testing.T) {
+	var got []TsCallData
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(data TsCallData) { got = append(got, data) }}}
+	WithInterpolation(250 * time.Millisecond)(n)
+
+	n.sample(snapshot("eth0", 0, 0), snapshot("eth0", 1000, 0), sampleTiming{elapsed: time.Second})
+	n.sample(snapshot("eth0", 1000, 0), snapshot("eth0", 3000, 0), sampleTiming{elapsed: time.Second})
+
+	// 第一次真实采样, 3 个插值点, 第二次真实采样
+	if len(got) != 5 {
+		t.Fatalf("got %d callbacks, want 5", len(got))
+	}
+	if got[0].Interpolated || got[4].Interpolated || got[0].BytesRx != 1000 || got[4].BytesRx != 2000 {
+		t.Fatalf("real samples = %+v, %+v", got[0], got[4])
+	}
+	for i, p := range got[1:4] {
+		if !p.Interpolated || p.Interval != 250*time.Millisecond {
+			t.Fatalf("point %d = %+v, want an interpolated 250ms point", i, p)
+		}
+		if p.BytesRxF <= got[i].BytesRxF || p.BytesRxF >= 2000 {
+			t.Fatalf("point %d: BytesRxF = %v, want strictly between %v and 2000", i, p.BytesRxF, got[i].BytesRxF)
+		}
+	}
+	if got[2].BytesRxF != 1500 || got[2].DeltaRx != 375 {
+		t.Fatalf("midpoint = %+v, want 1500 B/s and 375 bytes", got[2])
+	}
+}
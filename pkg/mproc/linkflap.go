@@ -0,0 +1,92 @@
+package mproc
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+const (
+	defaultLinkFlapWindow      = time.Minute // WithLinkFlapDetection 默认的统计窗口
+	defaultLinkFlapTransitions = 4           // 默认在窗口内 up/down 切换多少次时回调, 即两次完整的断开和恢复
+)
+
+// linkFlaps 统计每个接口在滑动窗口内 operstate 的切换次数, 只在采样协程中访问
+type linkFlaps struct {
+	callback func(iface string, ups, downs int, window time.Duration)
+	links    map[string]*linkState
+}
+
+// linkState 一个接口最近一次的 operstate 和窗口内的切换
+type linkState struct {
+	up       bool
+	changes  []linkChange
+	flapping bool // 已经回调, 切换次数回到阈值以下前不再回调
+}
+
+type linkChange struct {
+	at time.Time
+	up bool // 切换后是否为 up
+}
+
+// WithLinkFlapDetection 每次采样读取 sysfs 中各接口的 operstate, 统计滑动窗口内变为 up (ups) 和离开 up (downs) 的次数,
+// 两者之和达到阈值 (默认 1 分钟内 4 次, 见 WithLinkFlapThreshold) 时调用 callback, 用于解释吞吐量的下降.
+// 持续抖动时不重复调用, 切换次数回到阈值以下后重新开始检测; 采样之间来回切换的次数无法观察到
+func WithLinkFlapDetection(callback func(iface string, ups, downs int, window time.Duration)) netDevOpts {
+	return func(t *netDev) {
+		t.flaps = &linkFlaps{callback: callback, links: make(map[string]*linkState)}
+	}
+}
+
+// WithLinkFlapThreshold 设置 WithLinkFlapDetection 的阈值: window 内切换 transitions 次时回调
+func WithLinkFlapThreshold(transitions int, window time.Duration) netDevOpts {
+	return func(t *netDev) {
+		t.args.LinkFlapTransitions = transitions
+		t.args.LinkFlapWindow = window
+	}
+}
+
+// check 读取 cur 中各接口的 operstate, 第一次看到的接口只记录状态
+func (f *linkFlaps) check(root string, cur map[string]TsNetDev, at time.Time, transitions int, window time.Duration) {
+	if transitions <= 0 {
+		transitions = defaultLinkFlapTransitions
+	}
+	if window <= 0 {
+		window = defaultLinkFlapWindow
+	}
+	for name := range f.links {
+		if _, ok := cur[name]; !ok {
+			delete(f.links, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cur)) {
+		up := operUp(root, name)
+		l, ok := f.links[name]
+		if !ok {
+			f.links[name] = &linkState{up: up}
+			continue
+		}
+		if up != l.up {
+			l.up = up
+			l.changes = append(l.changes, linkChange{at: at, up: up})
+		}
+		l.changes = slices.DeleteFunc(l.changes, func(c linkChange) bool { return !c.at.After(at.Add(-window)) })
+		if len(l.changes) < transitions {
+			l.flapping = false
+			continue
+		}
+		if l.flapping {
+			continue
+		}
+		l.flapping = true
+		var ups, downs int
+		for _, c := range l.changes {
+			if c.up {
+				ups++
+			} else {
+				downs++
+			}
+		}
+		f.callback(name, ups, downs, window)
+	}
+}
@@ -0,0 +1,71 @@
+package mproc
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithLinkFlapDetection(t *testing.T) {
+	root := t.TempDir()
+	operstate := func(name, state string) {
+		writeFile(t, filepath.Join(root, "class", "net", name, "operstate"), state+"\n")
+	}
+	var events []string
+	n, err := newNetDev("test", time.Second, WithSysfsRoot(root), WithCallback(func(TsCallData) {}),
+		WithLinkFlapDetection(func(iface string, ups, downs int, window time.Duration) {
+			events = append(events, fmt.Sprintf("%s %d/%d %v", iface, ups, downs, window))
+		}),
+		WithLinkFlapThreshold(3, 10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// eth0 每秒在 up 和 down 之间切换, eth1 一直是 up
+	start := time.Unix(1700000000, 0)
+	stats := snapshot("eth0", 0, 0, "eth1", 0, 0)
+	states := []string{"up", "down", "up", "down", "down", "up", "up"}
+	for i, state := range states {
+		operstate("eth0", state)
+		operstate("eth1", "up")
+		n.sample(stats, stats, sampleTiming{elapsed: time.Second, at: start.Add(time.Duration(i) * time.Second)})
+	}
+	// 第 4 次采样时窗口内有 3 次切换, 持续抖动时不重复回调
+	if want := []string{"eth0 1/2 10s"}; !slices.Equal(events, want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+
+	// 切换移出窗口后重新检测
+	at := start.Add(30 * time.Second)
+	for _, state := range []string{"up", "down", "up", "down"} {
+		operstate("eth0", state)
+		n.sample(stats, stats, sampleTiming{elapsed: time.Second, at: at})
+		at = at.Add(time.Second)
+	}
+	if want := []string{"eth0 1/2 10s", "eth0 1/2 10s"}; !slices.Equal(events, want) {
+		t.Fatalf("events after the window = %q, want %q", events, want)
+	}
+}
+
+// 与 WithOnlyUp 一起使用时, 不是 up 的接口也要统计切换
+func TestLinkFlapWithOnlyUp(t *testing.T) {
+	root := t.TempDir()
+	var flaps int
+	n, err := newNetDev("test", time.Second, WithSysfsRoot(root), WithOnlyUp(true), WithCallback(func(TsCallData) {}),
+		WithLinkFlapDetection(func(string, int, int, time.Duration) { flaps++ }),
+		WithLinkFlapThreshold(2, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := snapshot("eth0", 0, 0)
+	start := time.Unix(1700000000, 0)
+	for i, state := range []string{"up", "down", "up"} {
+		writeFile(t, filepath.Join(root, "class", "net", "eth0", "operstate"), state+"\n")
+		n.sample(stats, stats, sampleTiming{elapsed: time.Second, at: start.Add(time.Duration(i) * time.Second)})
+	}
+	if flaps != 1 {
+		t.Fatalf("flap callbacks = %d, want 1", flaps)
+	}
+}
@@ -0,0 +1,121 @@
+package mproc
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Metric 指标, 可以按位组合, 用于 WithMetrics 选择要计算的指标和 WithAnomalyMetrics 选择要检测的速率
+type Metric uint32
+
+const (
+	MetricBytesRx      Metric = 1 << iota // 接收字节速率 BytesRx/BytesRxF/DeltaRx
+	MetricBytesTx                         // 发送字节速率 BytesTx/BytesTxF/DeltaTx
+	MetricPacketsRx                       // 接收包速率 PacketsRx
+	MetricPacketsTx                       // 发送包速率 PacketsTx
+	MetricErrsRx                          // 接收错误速率 ErrsRx
+	MetricErrsTx                          // 发送错误速率 ErrsTx
+	MetricDropRx                          // 接收丢包速率 DropRx
+	MetricDropTx                          // 发送丢包速率 DropTx
+	MetricPerInterface                    // 每个接口的速率 PerInterface
+	MetricAverage                         // 启动以来的平均速率 AvgBytesRx/AvgBytesTx
+
+	MetricPackets = MetricPacketsRx | MetricPacketsTx // 包速率 PacketsRx/PacketsTx
+	MetricErrors  = MetricErrsRx | MetricErrsTx       // 错误速率 ErrsRx/ErrsTx
+	MetricDrops   = MetricDropRx | MetricDropTx       // 丢包速率 DropRx/DropTx
+
+	MetricAll = MetricBytesRx | MetricBytesTx | MetricPackets | MetricErrors | MetricDrops | MetricPerInterface | MetricAverage
+)
+
+// metricNames 单个指标的名称, 与默认回调输出的日志字段一致
+var metricNames = map[Metric]string{
+	MetricBytesRx:      "bytes_rx",
+	MetricBytesTx:      "bytes_tx",
+	MetricPacketsRx:    "packets_rx",
+	MetricPacketsTx:    "packets_tx",
+	MetricErrsRx:       "errs_rx",
+	MetricErrsTx:       "errs_tx",
+	MetricDropRx:       "drop_rx",
+	MetricDropTx:       "drop_tx",
+	MetricPerInterface: "per_interface",
+	MetricAverage:      "average",
+}
+
+// Has 判断是否包含 m 中的所有指标
+func (s Metric) Has(m Metric) bool {
+	return s&m == m
+}
+
+// String 返回指标名称, 组合的指标以 | 连接, 例如 "bytes_rx|bytes_tx"; 没有任何指标时为 "none"
+func (s Metric) String() string {
+	if s == 0 {
+		return "none"
+	}
+	var names []string
+	for rest := s; rest != 0; rest &= rest - 1 {
+		bit := Metric(1) << bits.TrailingZeros32(uint32(rest))
+		name, ok := metricNames[bit]
+		if !ok {
+			name = fmt.Sprintf("Metric(%#x)", uint32(bit))
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseMetric 解析 String 返回的名称
+func ParseMetric(s string) (Metric, error) {
+	if s == "none" {
+		return 0, nil
+	}
+	var m Metric
+	for _, name := range strings.Split(s, "|") {
+		found := false
+		for bit, n := range metricNames {
+			if n == name {
+				m |= bit
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown metric: %q", name)
+		}
+	}
+	return m, nil
+}
+
+// rate 返回单个速率指标在采样中的值, 不是速率指标时返回 false
+func (s Metric) rate(data TsCallData) (float64, bool) {
+	switch s {
+	case MetricBytesRx:
+		return data.BytesRxF, true
+	case MetricBytesTx:
+		return data.BytesTxF, true
+	case MetricPacketsRx:
+		return float64(data.PacketsRx), true
+	case MetricPacketsTx:
+		return float64(data.PacketsTx), true
+	case MetricErrsRx:
+		return float64(data.ErrsRx), true
+	case MetricErrsTx:
+		return float64(data.ErrsTx), true
+	case MetricDropRx:
+		return float64(data.DropRx), true
+	case MetricDropTx:
+		return float64(data.DropTx), true
+	}
+	return 0, false
+}
+
+// interfaceRate 返回单个接口中该指标的速率, 只有字节速率按接口计算
+func (s Metric) interfaceRate(r TsInterfaceRate) (float64, bool) {
+	switch s {
+	case MetricBytesRx:
+		return r.BytesRxF, true
+	case MetricBytesTx:
+		return r.BytesTxF, true
+	}
+	return 0, false
+}
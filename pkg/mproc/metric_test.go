@@ -0,0 +1,62 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetricString(t *testing.T) {
+	for m, want := range map[Metric]string{
+		MetricBytesRx:                 "bytes_rx",
+		MetricDropTx:                  "drop_tx",
+		MetricPackets:                 "packets_rx|packets_tx",
+		MetricBytesRx | MetricAverage: "bytes_rx|average",
+		0:                             "none",
+	} {
+		if got := m.String(); got != want {
+			t.Errorf("%#x.String() = %q, want %q", uint32(m), got, want)
+		}
+		parsed, err := ParseMetric(want)
+		if err != nil || parsed != m {
+			t.Errorf("ParseMetric(%q) = %v, %v; want %v", want, parsed, err, m)
+		}
+	}
+	for bit := MetricBytesRx; bit <= MetricAverage; bit <<= 1 {
+		if parsed, err := ParseMetric(bit.String()); err != nil || parsed != bit {
+			t.Errorf("round trip of %v = %v, %v", bit, parsed, err)
+		}
+	}
+	if _, err := ParseMetric("bytes_rx|bogus"); err == nil {
+		t.Error("ParseMetric accepted an unknown name")
+	}
+}
+
+// WithMetrics 和 WithAnomalyMetrics 接受同一组指标
+func TestMetricOptions(t *testing.T) {
+	n := &netDev{args: &netDevArgs{}}
+	WithMetrics(MetricBytesRx, MetricDropRx)(n)
+	if n.args.Metrics != MetricBytesRx|MetricDropRx {
+		t.Fatalf("Metrics = %v", n.args.Metrics)
+	}
+	data := diff(snapshot("eth0", 0, 0), snapshot("eth0", 100, 100), time.Second, n.args.Metrics)
+	if data.BytesRx != 100 || data.BytesTx != 0 {
+		t.Fatalf("data = %+v, want only rx", data)
+	}
+
+	var fired []float64
+	WithAnomalyDetection(func(_ TsCallData, z float64) { fired = append(fired, z) })(n)
+	WithAnomalyMetrics(MetricDrops)(n)
+	if len(n.anomaly.metrics) != 2 || n.anomaly.metrics[0] != MetricDropRx || n.anomaly.metrics[1] != MetricDropTx {
+		t.Fatalf("anomaly metrics = %v, want drop_rx and drop_tx", n.anomaly.metrics)
+	}
+	for i := range 20 {
+		n.anomaly.check(TsCallData{DropRx: int64(10 + i%2), BytesRxF: 1e9})
+	}
+	if len(fired) != 0 {
+		t.Fatalf("fired = %v, byte rates are not checked", fired)
+	}
+	n.anomaly.check(TsCallData{DropRx: 1000})
+	if len(fired) != 1 {
+		t.Fatalf("fired = %v, want a drop_rx anomaly", fired)
+	}
+}
@@ -0,0 +1,38 @@
+package mproc
+
+import "time"
+
+// WithMinInterfaceRate 汇总速率只计入本次采样收发速率之和不低于 bytesPerSec 的接口, 过滤管理网口等的背景流量;
+// 包括所有接口的速率保存在 UnfilteredBytesRx 和 UnfilteredBytesTx 中. PerInterface 和 DeltaRx/DeltaTx 仍包括所有接口,
+// 因此 Summary 和 WithHistory 的字节总数不受下限影响
+func WithMinInterfaceRate(bytesPerSec int64) netDevOpts {
+	return func(t *netDev) {
+		t.args.MinInterfaceRate = bytesPerSec
+	}
+}
+
+// aboveFloor 返回 cur 中在 elapsed 内收发速率之和不低于 floor 的接口, 第一次出现的接口没有速率, 不计入
+func aboveFloor(prev, cur map[string]TsNetDev, elapsed time.Duration, floor int64) map[string]TsNetDev {
+	loud := make(map[string]TsNetDev, len(cur))
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok {
+			continue
+		}
+		delta := counterDelta(p.Receive.Bytes, c.Receive.Bytes) + counterDelta(p.Transmit.Bytes, c.Transmit.Bytes)
+		if perSecond(delta, elapsed) >= float64(floor) {
+			loud[name] = c
+		}
+	}
+	return loud
+}
+
+// useFloorAggregate 用 filtered 中的汇总速率替换 data 的汇总速率, 原来的速率保存到 Unfiltered 字段, 保留 data 的增量
+func useFloorAggregate(data *TsCallData, filtered TsCallData) {
+	data.UnfilteredBytesRx, data.UnfilteredBytesTx = data.BytesRx, data.BytesTx
+	data.BytesRx, data.BytesTx = filtered.BytesRx, filtered.BytesTx
+	data.BytesRxF, data.BytesTxF = filtered.BytesRxF, filtered.BytesTxF
+	data.PacketsRx, data.PacketsTx = filtered.PacketsRx, filtered.PacketsTx
+	data.ErrsRx, data.ErrsTx = filtered.ErrsRx, filtered.ErrsTx
+	data.DropRx, data.DropTx = filtered.DropRx, filtered.DropTx
+}
@@ -0,0 +1,34 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithMinInterfaceRate(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	WithMinInterfaceRate(1000)(n)
+
+	// 三个只有背景流量的接口和一个繁忙的接口, eth3 的收发之和刚好达到下限
+	prev := snapshot("eth0", 0, 0, "eth1", 0, 0, "eth2", 0, 0, "eth3", 0, 0, "eth9", 0, 0)
+	cur := snapshot("eth0", 100, 50, "eth1", 300, 0, "eth2", 0, 999, "eth3", 600, 400, "eth9", 50000, 20000)
+	data := n.sample(prev, cur, sampleTiming{elapsed: time.Second})
+	if data.BytesRx != 50600 || data.BytesTx != 20400 || data.DeltaRx != 51000 {
+		t.Fatalf("filtered aggregate = %d/%d (delta %d), want 50600/20400 and the unfiltered delta 51000", data.BytesRx, data.BytesTx, data.DeltaRx)
+	}
+	if data.UnfilteredBytesRx != 51000 || data.UnfilteredBytesTx != 21449 {
+		t.Fatalf("unfiltered aggregate = %d/%d, want 51000/21449", data.UnfilteredBytesRx, data.UnfilteredBytesTx)
+	}
+	if len(data.PerInterface) != 5 || data.PerInterface["eth1"].BytesRx != 300 {
+		t.Fatalf("PerInterface = %+v, want all interfaces", data.PerInterface)
+	}
+	if s := n.Summary(); s.BytesRx != 51000 || s.BytesTx != 21449 {
+		t.Fatalf("Summary bytes = %d/%d, want 51000/21449 including quiet interfaces", s.BytesRx, s.BytesTx)
+	}
+
+	// 下限按速率判断, 间隔变长时同样的增量低于下限
+	data = n.sample(prev, snapshot("eth3", 600, 400), sampleTiming{elapsed: 2 * time.Second})
+	if data.BytesRx != 0 || data.UnfilteredBytesRx != 300 {
+		t.Fatalf("over 2s: filtered %d, unfiltered %d; want 0 and 300", data.BytesRx, data.UnfilteredBytesRx)
+	}
+}
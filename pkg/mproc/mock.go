@@ -0,0 +1,45 @@
+package mproc
+
+import (
+	"maps"
+	"sync"
+)
+
+// MockSource 依次返回预先设定的快照的 StatsSource, 用于在没有 /proc 的环境中测试回调.
+// 与 WithClock 一起使用时整个采样流程是确定的
+type MockSource struct {
+	mu        sync.Mutex
+	snapshots []map[string]TsNetDev
+	reads     int
+}
+
+// NewMockSource 创建按顺序返回 snapshots 的数据源, 第一个快照作为基线
+func NewMockSource(snapshots ...map[string]TsNetDev) *MockSource {
+	return &MockSource{snapshots: snapshots}
+}
+
+// Add 在末尾追加快照, 可以在监控运行中调用
+func (m *MockSource) Add(snapshots ...map[string]TsNetDev) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots = append(m.snapshots, snapshots...)
+}
+
+// Read 返回下一个快照的副本; 快照用完后重复返回最后一个, 计数器不再变化, 速率为 0
+func (m *MockSource) Read() (map[string]TsNetDev, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.snapshots) == 0 {
+		return map[string]TsNetDev{}, nil
+	}
+	i := min(m.reads, len(m.snapshots)-1)
+	m.reads++
+	return maps.Clone(m.snapshots[i]), nil
+}
+
+// Reads 返回 Read 被调用的次数
+func (m *MockSource) Reads() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reads
+}
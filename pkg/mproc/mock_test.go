@@ -0,0 +1,111 @@
+package mproc
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMockSource(t *testing.T) {
+	m := NewMockSource(snapshot("eth0", 0, 0), snapshot("eth0", 100, 0))
+	for i, want := range []int64{0, 100, 100} {
+		stats, err := m.Read()
+		if err != nil || stats["eth0"].Receive.Bytes != want {
+			t.Fatalf("read %d = %+v, %v; want rx %d", i, stats, err, want)
+		}
+		stats["eth0"] = TsNetDev{} // 修改返回值不影响脚本
+	}
+	m.Add(snapshot("eth0", 500, 0))
+	if stats, _ := m.Read(); stats["eth0"].Receive.Bytes != 500 {
+		t.Fatalf("read after Add = %+v, want rx 500", stats)
+	}
+	if m.Reads() != 4 {
+		t.Fatalf("Reads = %d, want 4", m.Reads())
+	}
+}
+
+// stepClock 每次调用前进 step, 返回的时间没有单调时钟读数
+func stepClock(step time.Duration) func() time.Time {
+	var mu sync.Mutex
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		at = at.Add(step)
+		return at
+	}
+}
+
+func TestMockSourcePipeline(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	source := NewMockSource(
+		snapshot("eth0", 0, 0),
+		snapshot("eth0", 100, 50),
+		snapshot("eth0", 400, 50),
+	)
+	samples := make(chan TsCallData, 16)
+	n, err := NewNetDev("mock", interval,
+		WithStatsSource(source),
+		WithClock(stepClock(interval)),
+		WithCallback(func(data TsCallData) {
+			select {
+			case samples <- data:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	for i, want := range []struct{ rx, tx int64 }{{5000, 2500}, {15000, 0}} {
+		select {
+		case data := <-samples:
+			if data.BytesRx != want.rx || data.BytesTx != want.tx {
+				t.Fatalf("sample %d: rate %d/%d, want %d/%d", i+1, data.BytesRx, data.BytesTx, want.rx, want.tx)
+			}
+			if got := data.SampledAt.Sub(data.PrevSampledAt); got != interval {
+				t.Fatalf("sample %d: span %v, want %v", i+1, got, interval)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sample %d not delivered", i+1)
+		}
+	}
+}
+
+func ExampleMockSource() {
+	eth0 := func(rx int64) map[string]TsNetDev {
+		return map[string]TsNetDev{"eth0": {Name: "eth0", Receive: TsNetDevInfo{Bytes: rx}}}
+	}
+	source := NewMockSource(eth0(0), eth0(100), eth0(400))
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		at = at.Add(100 * time.Millisecond) // 只在采样协程中调用
+		return at
+	}
+
+	samples := make(chan TsCallData, 8)
+	monitor, err := NewNetDev("mock", 100*time.Millisecond,
+		WithStatsSource(source),
+		WithClock(clock),
+		WithCallback(func(data TsCallData) {
+			select {
+			case samples <- data:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		panic(err)
+	}
+	defer monitor.Close()
+
+	// 输出 00:00:00.200 100 1000 和 00:00:00.300 300 3000;
+	// mlog 异步写入标准输出, 其他测试的日志可能混入, 因此不校验输出, 由 TestMockSourcePipeline 验证
+	for range 2 {
+		data := <-samples
+		fmt.Println(data.SampledAt.Format("15:04:05.000"), data.DeltaRx, data.BytesRx)
+	}
+}
@@ -0,0 +1,44 @@
+package mproc
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// ndjsonWriter 每个采样写一行紧凑的 JSON
+type ndjsonWriter struct {
+	mu sync.Mutex // 保证每行完整写入并刷新后才写下一行
+	w  io.Writer
+}
+
+func (n *ndjsonWriter) Write(data TsCallData) error {
+	line, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.w.Write(line); err != nil {
+		return err
+	}
+	// 带缓冲的 w (例如 bufio.Writer) 每行刷新一次
+	if f, ok := n.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close 不关闭 w, w 通常是 os.Stdout 等由调用方管理的输出
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// WithNDJSON 每个采样向 w 写入一行紧凑的 JSON, 便于交给 jq 或日志采集; 每行只调用一次 w.Write,
+// 多个监控写入同一个文件时各行不会交错. 监控关闭时不关闭 w
+func WithNDJSON(w io.Writer) netDevOpts {
+	return func(t *netDev) {
+		WithSink(&ndjsonWriter{w: w})(t)
+	}
+}
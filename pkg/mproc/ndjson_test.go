@@ -0,0 +1,42 @@
+package mproc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	n := newFIFONetDev(t, 50*time.Millisecond, WithNDJSON(bw))
+	n.feed(netDevFile("eth0", 0, 0))
+	for _, rx := range []int{1000, 3000} {
+		n.feed(netDevFile("eth0", rx, 0))
+		n.next()
+	}
+	n.Close()
+
+	// 每行都已刷新, 不需要调用方 Flush
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v\n%s", i, err, line)
+		}
+		for _, key := range []string{"Name", "BytesRx", "BytesTx", "DeltaRx", "Interval", "PerInterface"} {
+			if _, ok := obj[key]; !ok {
+				t.Errorf("line %d: missing key %q", i, key)
+			}
+		}
+		if obj["Name"] != "test" || obj["DeltaRx"] != float64(1000*(i+1)) {
+			t.Errorf("line %d = %s", i, line)
+		}
+	}
+}
@@ -2,9 +2,19 @@ package mproc
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
@@ -13,8 +23,72 @@ import (
 )
 
 type netDev struct {
-	args *netDevArgs
-	done chan struct{} // 用于信号goroutine退出的通道
+	args       *netDevArgs
+	done       chan struct{}            // 用于信号goroutine退出的通道
+	closeOnce  sync.Once                // 保证 done 只被关闭一次
+	sdReady    bool                     // 是否已向 systemd 发送 READY=1
+	history    *history                 // 历史数据, 需要 WithHistory
+	adaptive   *adaptiveInterval        // 自适应采样间隔, 需要 WithAdaptiveInterval
+	smoother   *smoother                // 速率平滑, 需要 WithSmoothing
+	stopped    chan struct{}            // 采样协程退出后关闭
+	sinks      []Sink                   // 每次采样后写入的输出
+	pool       *CallbackPool            // 执行回调的协程池, 需要 WithCallbackPool
+	queue      *poolQueue               // 本监控在 pool 中的回调队列
+	inflight   *inflightLimiter         // 限制同时执行的回调数量, 需要 WithMaxInflightCallbacks
+	pods       *podTagger               // veth 接口的 Pod 归属, 需要 WithPodResolver
+	types      *typeTracker             // 接口类型, 需要 WithInterfaceTypes
+	details    *detailTracker           // 接口的 MTU 和队列数, 需要 WithInterfaceDetails
+	classes    *classTracker            // 各流量类的速率, 需要 WithTrafficClasses
+	anomaly    *anomalyDetector         // 速率异常检测, 需要 WithAnomalyDetection
+	source     StatsSource              // 计数器来源, 需要 WithStatsSource
+	renames    *renameTracker           // 接口改名跟踪, 需要 WithRenameTracking
+	metrics    *openMetrics             // OpenMetrics 输出, 需要 WithOpenMetrics
+	summary    summary                  // 启动以来的汇总
+	seen       ifaceSet                 // 最近一次读取到的接口, 见 Interfaces
+	files      map[string]*preadFile    // 保持打开的网络设备文件, 需要 WithKeepOpen, 只在采样协程中访问
+	shared     map[string]*sharedReader // 共享读取的状态, 需要 WithSharedRead, 只在采样协程中访问
+	duplicates map[string]bool          // 已经警告过的同名接口, 需要 WithDuplicateInterfaces(DuplicateSum)
+	skipped    map[string]bool          // 上一次读取中被跳过的行, 持续存在的行只警告一次
+	last       *TsCallData              // 上一次回调的真实采样, 需要 WithInterpolation, 只在采样协程中访问
+	fatal      error                    // 采样协程因错误退出的原因, 见 Err
+	fatalMu    sync.Mutex               // 保护 fatal
+	dedup      *dedup                   // 抑制重复的采样, 需要 WithDedup
+	tcp        *tcpTracker              // TCP 重传率, 需要 WithRetransRatio
+	baseline   *rateBaseline            // 按多个间隔计算速率, 需要 WithRateBaseline, 只在采样协程中访问
+	thresholds []*ifaceThreshold        // 每个接口的速率阈值, 需要 WithInterfaceThreshold
+	spikes     *errorSpikes             // 每个接口的错误速率突增, 需要 WithErrorSpikeCallback
+	flaps      *linkFlaps               // 每个接口的 up/down 切换, 需要 WithLinkFlapDetection
+	batch      *batchSink               // 批量回调, 需要 WithBatchCallback, 同时在 sinks 中
+	pulled     *pullState               // RateSince 上一次调用时的读取, 只在采样协程中访问
+	replaying  bool                     // NewReplay 的监控, 没有可读取的文件
+	streams    streams                  // StreamAggregate 和 StreamPerInterface 的订阅者
+
+	now      func() time.Time // 时钟, 为 nil 时使用 time.Now, 见 WithClock
+	deadline time.Time        // 自动关闭的时间, 需要 WithMaxLifetime
+
+	// 上一次采样的速率, 用于计算 DeltaPercentRx/DeltaPercentTx, 只在采样协程中访问
+	lastRate struct {
+		rx, tx float64
+		ok     bool
+	}
+
+	// 启动以来的总字节数和各采样间隔之和, 只在采样协程中访问
+	totals struct {
+		rx, tx  int64
+		elapsed time.Duration
+	}
+
+	reconfig   chan func()           // Reconfigure 交给采样协程执行的替换
+	flushes    chan chan flushResult // Flush 的请求
+	reconfigMu sync.Mutex            // 保证同一时间只有一个 Reconfigure
+}
+
+// Sink 采样数据的输出目标, 每次采样后调用 Write, 监控关闭时调用 Close
+//
+// Write 在采样协程中调用, 耗时的输出应自行缓冲, 参考 WithRemoteWrite
+type Sink interface {
+	Write(data TsCallData) error
+	Close() error
 }
 
 type netDevArgs struct {
@@ -23,37 +97,196 @@ type netDevArgs struct {
 
 	Callback   func(data TsCallData) // 保存数据的回调函数
 	Interfaces []string              // 需要监控的接口
+	interfaces map[string]struct{}   // Interfaces 的集合, 由 WithInterfaces 创建
 	Path       string                // 网络设备文件路径
+	SysfsRoot  string                // sysfs 挂载点, 用于读取接口属性
+	BondMode   BondMode              // bond 接口的统计方式
+	Normalize  func() float64        // 归一化除数, 例如 CPU 核数或链路速率
+	PID        int                   // 监控的进程 PID, 为 0 时表示监控主机
+
+	Labels  map[string]string // 附加到每个采样的标签, 包括 hostname
+	Metrics Metric            // 需要计算的指标
+
+	PacketMetrics bool // 默认日志是否输出包速率
+	ErrorMetrics  bool // 默认日志是否输出错误和丢包速率
+
+	LockOSThread bool    // 采样协程是否独占一个系统线程
+	SkipIdle     bool    // 是否忽略从未收发过数据的接口
+	AlignedTicks bool    // 采样时间是否对齐到 interval 的整数倍
+	ReplaySpeed  float64 // 回放速度倍数, 为 0 时不等待, 需要 NewReplay
+
+	MonotonicRates bool // 是否按实际测量的单调时钟间隔计算速率
+
+	MaxLifetime time.Duration             // 运行时长上限, 为 0 时不限制
+	OnFinish    func(data TsCallData)     // 到达运行时长上限时调用, 参数为最后一次采样
+	OnStart     func(interfaces []string) // 第一次读取基线成功后调用, 参数为监控的接口
+
+	SystemdNotify bool // 是否向 systemd 发送 READY/WATCHDOG 通知
+	Heartbeat     bool // 没有可报告的速率时也每个周期回调一次
+	Exemplars     bool // OpenMetrics 输出的计数器是否附带 exemplar
+
+	Paths          []string      // 需要合并读取的多个网络设备文件, 设置后忽略 Path
+	ConcurrentRead bool          // 是否并发读取 Paths 中的文件以减小读取偏差
+	HeaderMapping  bool          // 是否按表头中的列名而不是位置解析字段
+	KeepOpen       bool          // 是否保持文件打开并用 pread 读取
+	SharedRead     bool          // 是否与其他监控共享同一文件的读取
+	SharedReadTTL  time.Duration // 共享读取的有效期, 为 0 时为 Interval 的一半
+	Warmup         int           // 基线之后丢弃的采样次数
+	Interpolation  time.Duration // 两次真实采样之间插值的间隔
+	SnmpPath       string        // WithRetransRatio 读取的 snmp 文件
+	Duplicates     DuplicateMode // Paths 中出现同名接口时的处理方式
+	MaxReadErrors  int           // 连续读取失败多少次后停止监控, 为 0 时不限制
+
+	ErrorSpikeFloor int64 // WithErrorSpikeCallback 的下限, 每秒的错误或丢包数
+
+	MinInterfaceRate int64 // 计入汇总的接口的最低速率, 每秒收发字节数之和, 为 0 时不过滤
+
+	SinkPolicy       SinkPolicy              // 多个输出时的写入方式
+	SinkErrorHandler func(s Sink, err error) // 输出的错误, 为 nil 时记录日志
+
+	NameResolver func(raw string) string // 过滤前对接口名的映射, 见 WithNameResolver
+
+	LinkFlapTransitions int           // WithLinkFlapDetection 在窗口内回调的切换次数, 为 0 时为 4
+	LinkFlapWindow      time.Duration // WithLinkFlapDetection 的统计窗口, 为 0 时为 1 分钟
+
+	Derived map[string]func(data TsCallData) float64 // 派生指标, 见 WithDerived
+	Gate    func() bool                              // 每个周期读取前调用, 返回 false 时跳过本次采样
+	OnReset func(iface string)                       // 接口的计数器被重置时调用, 见 WithResetCallback
+
+	BatchSize int // WithBatchCallback 每批的采样数
+
+	Groups map[string][]string // 接口组, 键为组名, 见 WithGroup
+
+	OnlyUp      bool // 只计算 operstate 为 up 的接口
+	Quiet       bool // 不输出信息和警告日志
+	QuietErrors bool // 不输出错误日志
+
+	PanicPolicy  PanicPolicy         // 采样协程 panic 时的处理方式
+	PanicHandler func(recovered any) // PanicCallback 时调用
 }
 type netDevOpts func(*netDev)
 
+// MinInterval 允许的最小采样间隔, 过小的间隔会频繁读取 /proc 占用 CPU
+const MinInterval = 10 * time.Millisecond
+
 // NewNetDev 读取并解析网络设备文件
 func NewNetDev(name string, interval time.Duration, opts ...netDevOpts) (*netDev, error) {
+	t, err := newNetDev(name, interval, opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.start()
+	return t, nil
+}
+
+// newNetDev 创建监控并应用选项, 不启动采样协程
+func newNetDev(name string, interval time.Duration, opts ...netDevOpts) (*netDev, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid interval: %v, must be positive", interval)
+	}
+	if interval < MinInterval {
+		return nil, fmt.Errorf("invalid interval: %v, must be at least %v", interval, MinInterval)
+	}
+	hostname, hostErr := os.Hostname()
 	t := &netDev{
 		args: &netDevArgs{
 			Name:       name,
 			Interval:   interval,
 			Interfaces: nil,
-			Callback: func(data TsCallData) {
-				mlog.Info(mlog.H{
-					"name":       data.Name,
-					"bytes_tx":   data.BytesTx,
-					"bytes_rx":   data.BytesRx,
-					"interfaces": data.Interfaces,
-					"interval":   data.Interval,
-				})
-			},
-			Path: "/proc/net/dev",
+			Path:       "/proc/net/dev",
+			SysfsRoot:  "/sys",
+			Metrics:    MetricAll,
+			Labels:     map[string]string{},
 		},
-		done: make(chan struct{}),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		reconfig: make(chan func()),
+		flushes:  make(chan chan flushResult),
+	}
+	t.args.Callback = t.logSample
+	if hostname != "" {
+		t.args.Labels["hostname"] = hostname
 	}
 	for _, opt := range opts {
 		opt(t)
 	}
-	t.start()
+	if hostErr != nil && !t.args.Quiet {
+		mlog.Warn(mlog.H{"msg": "failed to get hostname", "error": hostErr.Error()})
+	}
+	if t.args.Interval < MinInterval {
+		t.closeSinks()
+		return nil, fmt.Errorf("invalid interval: %v, must be at least %v", t.args.Interval, MinInterval)
+	}
+	if err := t.checkThresholds(); err != nil {
+		t.closeSinks()
+		return nil, err
+	}
+	if t.renames != nil {
+		t.renames = newRenameTracker(t.args.SysfsRoot)
+	}
+	if t.types != nil {
+		t.types = newTypeTracker(t.args.SysfsRoot)
+	}
+	if t.details != nil {
+		t.details = newDetailTracker(t.args.SysfsRoot)
+	}
+	if t.batch != nil {
+		t.batch.size = max(t.args.BatchSize, 1)
+		t.sinks = append(t.sinks, t.batch)
+	}
+	quietErrors := func() bool { return t.args.QuietErrors } // Reconfigure 替换 args 后仍读取最新的值
+	if t.tcp != nil {
+		t.tcp.quietErrors = quietErrors
+		t.tcp.path = t.args.SnmpPath
+		if t.tcp.path == "" {
+			t.tcp.path = filepath.Join(filepath.Dir(t.args.Path), "snmp") // /proc/net/dev -> /proc/net/snmp
+		}
+	}
+	if t.classes != nil {
+		t.classes.quietErrors = quietErrors
+	}
+	t.openFiles()
+	t.args.Callback = t.guardCallback(t.args.Callback)
+	t.guardHooks()
+	if t.pool != nil {
+		t.queue = newPoolQueue(t.pool, t.args.Callback)
+		t.args.Callback = t.queue.dispatch
+	} else if t.inflight != nil && t.inflight.sem != nil {
+		t.inflight.callback = t.args.Callback
+		t.args.Callback = t.inflight.dispatch
+	}
 	return t, nil
 }
 
+// NewNetDevForPID 监控指定进程所在网络命名空间的 /proc/<pid>/net/dev
+//
+// 进程退出后文件消失, 监控会自动停止
+func NewNetDevForPID(pid int, name string, interval time.Duration, opts ...netDevOpts) (*netDev, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid: %d", pid)
+	}
+	pidOpts := []netDevOpts{
+		WithPath(fmt.Sprintf("/proc/%d/net/dev", pid)),
+		func(t *netDev) { t.args.PID = pid },
+	}
+	return NewNetDev(name, interval, append(pidOpts, opts...)...)
+}
+
+// WithInterval 设置采样间隔, 主要用于 Reconfigure
+func WithInterval(interval time.Duration) netDevOpts {
+	return func(t *netDev) {
+		t.args.Interval = interval
+	}
+}
+
+// WithInterfaces 只监控指定的接口, 不设置时监控所有接口
+func WithInterfaces(ifaces ...string) netDevOpts {
+	return func(t *netDev) {
+		t.args.Interfaces = ifaces
+		t.args.interfaces = interfaceSet(ifaces)
+	}
+}
+
 // WithPath 设置网络设备文件路径
 func WithPath(path string) netDevOpts {
 	return func(t *netDev) {
@@ -61,6 +294,82 @@ func WithPath(path string) netDevOpts {
 	}
 }
 
+// WithSysfsRoot 设置 sysfs 挂载点, 默认为 /sys
+func WithSysfsRoot(root string) netDevOpts {
+	return func(t *netDev) {
+		t.args.SysfsRoot = root
+	}
+}
+
+// WithProcRoot 设置 procfs 挂载点, 默认为 /proc, 例如容器中挂载的宿主机 /host/proc:
+// 网络设备文件为 <root>/net/dev (NewNetDevForPID 为 <root>/<pid>/net/dev), sysfs 为同一目录下的 sys (/host/sys),
+// WithRetransRatio 的 snmp 文件随网络设备文件一起移动. 之后的 WithPath 和 WithSysfsRoot 可以单独覆盖
+func WithProcRoot(root string) netDevOpts {
+	return func(t *netDev) {
+		if t.args.PID > 0 {
+			t.args.Path = filepath.Join(root, fmt.Sprint(t.args.PID), "net", "dev")
+		} else {
+			t.args.Path = filepath.Join(root, "net", "dev")
+		}
+		t.args.SysfsRoot = filepath.Join(filepath.Dir(filepath.Clean(root)), "sys")
+	}
+}
+
+// WithRenameTracking 通过 sysfs 中的 ifindex 跟踪改名的接口, 改名后计数器延续到新名称,
+// 不会出现旧接口消失、新接口从头作为基线的情况
+func WithRenameTracking(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.renames = nil
+		if enabled {
+			t.renames = &renameTracker{}
+		}
+	}
+}
+
+// WithBondAggregation 通过 sysfs 中的 bonding/slaves 识别 bond 接口, 按 mode 统计 bond 或其成员,
+// 避免 bond 与成员的流量在汇总中重复计算
+func WithBondAggregation(mode BondMode) netDevOpts {
+	return func(t *netDev) {
+		t.args.BondMode = mode
+	}
+}
+
+// WithPaths 同时读取多个网络设备文件并合并, 例如多个网络命名空间
+func WithPaths(paths ...string) netDevOpts {
+	return func(t *netDev) {
+		t.args.Paths = paths
+	}
+}
+
+// WithConcurrentRead 并发读取 WithPaths 中的文件, 使各文件的读取时间尽量接近
+func WithConcurrentRead(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.ConcurrentRead = enabled
+	}
+}
+
+// WithWarmup 在基线之后丢弃前 n 次采样, 刚启动的接口计数器稳定后才开始回调
+func WithWarmup(n int) netDevOpts {
+	return func(t *netDev) {
+		t.args.Warmup = n
+	}
+}
+
+// WithHeaderMapping 按表头中的列名确定字段顺序, 适用于列顺序与内核不同的容器环境;
+// 没有可识别的表头时仍按位置解析
+func WithHeaderMapping(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.HeaderMapping = enabled
+	}
+}
+
+// WithStatsSource 从 source 读取计数器, 代替读取网络设备文件, 例如 NewBPFMapSource
+func WithStatsSource(source StatsSource) netDevOpts {
+	return func(t *netDev) {
+		t.source = source
+	}
+}
+
 // WithCallback 设置回调函数
 func WithCallback(callback func(data TsCallData)) netDevOpts {
 	return func(t *netDev) {
@@ -68,19 +377,294 @@ func WithCallback(callback func(data TsCallData)) netDevOpts {
 	}
 }
 
+// WithMetrics 只计算和填充指定的指标, 其他字段保持零值, 默认为 MetricAll
+func WithMetrics(metrics ...Metric) netDevOpts {
+	return func(t *netDev) {
+		t.args.Metrics = 0
+		for _, m := range metrics {
+			t.args.Metrics |= m
+		}
+	}
+}
+
+// WithLabels 为每个采样附加标签, 与默认的 hostname 标签合并, 同名时覆盖
+func WithLabels(labels map[string]string) netDevOpts {
+	return func(t *netDev) {
+		maps.Copy(t.args.Labels, labels)
+	}
+}
+
+// WithCallbackPool 在共享的协程池中执行回调, 采样协程不会被耗时的回调阻塞
+//
+// 同一个监控的回调保持采样顺序, Close 后已提交的回调仍会执行;
+// 待执行的回调超过 1024 个时丢弃新采样, 见 DroppedCallbacks
+func WithCallbackPool(pool *CallbackPool) netDevOpts {
+	return func(t *netDev) {
+		t.pool = pool
+	}
+}
+
+// WithPacketMetrics 默认日志额外输出 packets_rx/packets_tx, 包速率本身总是在 TsCallData 中计算
+func WithPacketMetrics(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.PacketMetrics = enabled
+	}
+}
+
+// WithErrorMetrics 默认日志额外输出 errs/drop (接收和发送之和)
+func WithErrorMetrics(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.ErrorMetrics = enabled
+	}
+}
+
+// WithLockOSThread 将采样协程固定在一个系统线程上, 配合调用方设置的 CPU 亲和性可以减小调度抖动
+//
+// 该线程在监控关闭前不会执行其他协程, 会多占用一个线程, 只在对采样时间敏感时使用
+func WithLockOSThread(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.LockOSThread = enabled
+	}
+}
+
+// WithSkipIdle 从 PerInterface 和 Interfaces 中去掉累计收发字节数为 0 的接口, 开始收发数据后重新报告
+func WithSkipIdle(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.SkipIdle = enabled
+	}
+}
+
+// WithAlignedTicks 将采样对齐到墙上时钟 interval 的整数倍, 例如 10 秒间隔时在 :00, :10, :20 采样
+//
+// 首次采样会延迟到下一个整点; 每次采样后都重新计算, 时钟跳变后自动恢复对齐
+func WithAlignedTicks(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.AlignedTicks = enabled
+	}
+}
+
+// WithMonotonicRates 按两次读取之间实际经过的单调时钟时间计算速率, 而不是名义上的 interval
+//
+// 采样协程被延迟时速率更准确, 不受墙上时钟跳变影响; 无法测量时仍使用 interval
+func WithMonotonicRates(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.MonotonicRates = enabled
+	}
+}
+
+// WithMaxLifetime 运行 d 后自动关闭: 先做最后一次采样 (间隔按实际经过的时间计算),
+// 再以该采样调用 onFinish (可以为 nil), 之前没有基线时采样为零值. 提前调用 Close 时不会调用 onFinish
+func WithMaxLifetime(d time.Duration, onFinish func(data TsCallData)) netDevOpts {
+	return func(t *netDev) {
+		t.args.MaxLifetime = d
+		t.args.OnFinish = onFinish
+	}
+}
+
+// WithMaxReadErrors 连续 n 次读取失败后停止监控, Wait 和 Err 返回包装了 ErrReadFailed 的最后一次读取错误;
+// 为 0 (默认) 时读取失败只记录日志, 一直重试
+func WithMaxReadErrors(n int) netDevOpts {
+	return func(t *netDev) {
+		t.args.MaxReadErrors = n
+	}
+}
+
+// WithOnStart 第一次读取基线成功后在采样协程中调用一次 fn, 参数为按名称排序的监控接口,
+// 可以在第一次采样之前确认配置或发出就绪信号. 修改间隔后重新读取基线时不再调用
+func WithOnStart(fn func(interfaces []string)) netDevOpts {
+	return func(t *netDev) {
+		t.args.OnStart = fn
+	}
+}
+
+// WithGate 每个周期读取前在采样协程中调用 fn, 返回 false 时不读取也不回调, 例如根据标志文件或电源状态暂停监控;
+// 再次返回 true 时先重新读取基线, 下一个周期才回调, 暂停期间的流量不计入速率
+func WithGate(fn func() bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.Gate = fn
+	}
+}
+
+// WithNormalize 设置归一化除数, 回调数据中会额外计算 NormalizedRx/NormalizedTx
+func WithNormalize(divisor func() float64) netDevOpts {
+	return func(t *netDev) {
+		t.args.Normalize = divisor
+	}
+}
+
+// WithSystemdNotify 每次成功采样后向 systemd 发送 WATCHDOG=1, 首次采样后发送 READY=1
+func WithSystemdNotify(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.SystemdNotify = enabled
+	}
+}
+
+// WithHeartbeat 保证每个周期至少回调一次: 首次读取和读取失败时回调零速率的心跳,
+// 没有流量时的采样同样标记为心跳, 以便区分 "监控停止" 和 "没有流量"
+func WithHeartbeat(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.Heartbeat = enabled
+	}
+}
+
+// WithAdaptiveInterval 根据速率变化自动调整采样间隔, 平稳或空闲时逐步延长到 max, 突变时缩短到 min
+//
+// 设置后忽略 NewNetDev 的 interval, 当前间隔通过 TsCallData.Interval 报告; 间隔变化后的第一次采样
+// 按实际经过的时间计算速率, Interval 也是实际经过的时间
+func WithAdaptiveInterval(min, max time.Duration) netDevOpts {
+	return func(t *netDev) {
+		t.adaptive = newAdaptiveInterval(min, max)
+	}
+}
+
+// WithSmoothing 以指数加权移动平均平滑速率, 汇总和每个接口分别维护各自的平均值
+//
+// alpha 取值 (0, 1], 越小越平滑, 结果写入 SmoothedRx/SmoothedTx
+func WithSmoothing(alpha float64) netDevOpts {
+	return func(t *netDev) {
+		t.smoother = newSmoother(alpha)
+	}
+}
+
+// WithPodResolver 为 PerInterface 中的 veth 接口标记所属的 Pod 和命名空间
+func WithPodResolver(resolve PodResolver) netDevOpts {
+	return func(t *netDev) {
+		t.pods = newPodTagger(resolve)
+	}
+}
+
+// WithTrafficClasses 为 PerInterface 中的接口附加各流量类 (tc class) 的字节和丢包速率,
+// 类计数由 provide 提供, 使核心不依赖 tc 或 netlink
+func WithTrafficClasses(provide ClassStatsProvider) netDevOpts {
+	return func(t *netDev) {
+		t.classes = newClassTracker(provide)
+	}
+}
+
+// WithAnomalyDetection 维护最近 60 个采样的速率均值和标准差,
+// 当接收或发送速率的 z-score 绝对值超过阈值 (默认 3, 见 WithAnomalySigma) 时调用 onAnomaly
+func WithAnomalyDetection(onAnomaly func(data TsCallData, z float64)) netDevOpts {
+	return func(t *netDev) {
+		sigma := anomalySigma
+		if t.anomaly != nil {
+			sigma = t.anomaly.sigma
+		}
+		var metrics []Metric
+		if t.anomaly != nil {
+			metrics = t.anomaly.metrics
+		}
+		t.anomaly = &anomalyDetector{sigma: sigma, metrics: metrics, onAnomaly: onAnomaly}
+	}
+}
+
+// WithAnomalyMetrics 设置异常检测的速率指标, 例如 MetricDropRx; 默认检测 MetricBytesRx 和 MetricBytesTx.
+// 组合指标会拆成单个指标分别检测, 需要同时使用 WithAnomalyDetection
+func WithAnomalyMetrics(metrics ...Metric) netDevOpts {
+	return func(t *netDev) {
+		if t.anomaly == nil {
+			t.anomaly = &anomalyDetector{sigma: anomalySigma, onAnomaly: func(TsCallData, float64) {}}
+		}
+		t.anomaly.metrics = nil
+		for _, m := range metrics {
+			for rest := m; rest != 0; rest &= rest - 1 {
+				t.anomaly.metrics = append(t.anomaly.metrics, rest&-rest)
+			}
+		}
+		t.anomaly.stats = nil
+	}
+}
+
+// WithAnomalySigma 设置异常检测的 z-score 阈值, 需要同时使用 WithAnomalyDetection
+func WithAnomalySigma(sigma float64) netDevOpts {
+	return func(t *netDev) {
+		if t.anomaly == nil {
+			t.anomaly = &anomalyDetector{onAnomaly: func(TsCallData, float64) {}}
+		}
+		t.anomaly.sigma = sigma
+	}
+}
+
+// WithHistory 保留历史采样数据, 超过 1 分钟的数据按 10 秒聚合, 超过 1 小时的按 1 分钟聚合
+//
+// retention 为各层共同的最长保留时长, 为 0 时不限制
+func WithHistory(retention time.Duration) netDevOpts {
+	return func(t *netDev) {
+		t.history = newHistory(retention)
+	}
+}
+
+// History 返回按 resolution 降采样后的历史数据, 未启用 WithHistory 时返回 nil
+func (t *netDev) History(resolution time.Duration) []TsHistoryPoint {
+	if t.history == nil {
+		return nil
+	}
+	return t.history.points(resolution)
+}
+
+// BytesSince 返回 since 之后接收和发送的总字节数, 用于计费或配额.
+// 需要 WithHistory; since 早于保留的历史数据或未启用 WithHistory 时 ok 为 false.
+// 超过 1 分钟的数据已经聚合, 与 since 相交的聚合区间整体计入
+func (t *netDev) BytesSince(since time.Time) (rx, tx int64, ok bool) {
+	if t.history == nil {
+		return 0, 0, false
+	}
+	return t.history.bytesSince(since)
+}
+
+// WithSink 添加输出, 可以多次使用; 单个输出失败只记录日志, 不影响其他输出
+func WithSink(s Sink) netDevOpts {
+	return func(t *netDev) {
+		t.sinks = append(t.sinks, s)
+	}
+}
+
+// WithRemoteWrite 按 interval 批量将采样以 Prometheus remote-write 协议推送到 url
+func WithRemoteWrite(url string, interval time.Duration) netDevOpts {
+	return func(t *netDev) {
+		WithSink(newRemoteWriter(url, interval))(t)
+	}
+}
+
+// WithOpenMetrics 保留每个接口的最近采样, 通过 OpenMetricsHandler 以 OpenMetrics 文本格式暴露
+func WithOpenMetrics() netDevOpts {
+	return func(t *netDev) {
+		t.metrics = newOpenMetrics()
+	}
+}
+
+// WithExemplars OpenMetrics 输出的计数器附带 exemplar, 包含接口名和精确的采样时间, 便于与链路追踪关联
+func WithExemplars(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.Exemplars = enabled
+	}
+}
+
+// OpenMetricsHandler 返回输出最近采样的 http.Handler, 未启用 WithOpenMetrics 时返回 nil
+func (t *netDev) OpenMetricsHandler() http.Handler {
+	if t.metrics == nil {
+		return nil
+	}
+	return t.metrics
+}
+
 // Close 关闭netDev并停止所有goroutine
 func (t *netDev) Close() {
-	close(t.done)
+	t.stop()
+	<-t.stopped
 }
 
-func (t *netDev) start() {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				mlog.Error(mlog.H{"error": "netDev goroutine panic", "reason": r})
-			}
-		}()
+// stop 通知采样协程退出, 可以在采样协程内部调用
+func (t *netDev) stop() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+}
 
+func (t *netDev) start() {
+	if t.args.MaxLifetime > 0 {
+		t.deadline = time.Now().Add(t.args.MaxLifetime)
+	}
+	t.run(func() {
 		for {
 			select {
 			case <-t.done:
@@ -89,137 +673,871 @@ func (t *netDev) start() {
 				t.calculate()
 			}
 		}
+	})
+}
+
+// run 在采样协程中执行 fn, 退出时关闭所有输出
+func (t *netDev) run(fn func()) {
+	go func() {
+		if t.args.LockOSThread {
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+		}
+		defer close(t.stopped)
+		defer t.stop() // 因错误或 panic 退出时 Reconfigure 等不再等待采样协程
+		defer t.closeSinks()
+		defer t.streams.close()
+		defer t.closeFiles()
+		defer t.releaseShared()
+		defer t.recoverPanic()
+
+		fn()
 	}()
 }
 
 // 传入回调函数
 func (n *netDev) calculate() {
-	var lastRx, lastTx int64
-	firstIteration := true // 是否为第一次迭代
+	var lastStats map[string]TsNetDev
+	var lastRead time.Time  // 上一次读取完成的时间, 带单调时钟读数
+	var lastLate bool       // 上一次读取是否晚于定时器触发
+	firstIteration := true  // 是否为第一次迭代
+	warmup := n.args.Warmup // 基线之后还需要丢弃的采样次数
+	readErrors := 0         // 连续读取失败的次数
+	started := false        // 是否已调用 OnStart
+	resized := false        // 间隔在上一次读取之后变化, 定时器重新计时
 
-	ticker := time.NewTicker(n.args.Interval) // 每 interval 执行一次
+	interval := n.args.Interval
+	if n.adaptive != nil {
+		interval = n.adaptive.current
+	}
+	ticker := time.NewTicker(n.nextTick(interval)) // 每 interval 执行一次
 	defer ticker.Stop()
 
+	var expire <-chan time.Time // 到达 WithMaxLifetime 设置的时长
+	if !n.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(n.deadline))
+		defer timer.Stop()
+		expire = timer.C
+	}
+
 	for {
 		select {
 		case <-n.done:
 			return // 收到关闭信号时退出
-		case <-ticker.C:
-			totalRx, totalTx := int64(0), int64(0)
-			stats, err := n.readNetDev() // 获取当前所有接口的数据
+		case <-expire:
+			var data TsCallData
+			begin := time.Now()
+			stats, skew, err := n.readNetDev()
+			took := time.Since(begin)
+			readAt := n.readTime()
+			if err == nil && !firstIteration {
+				prev := lastStats
+				if n.renames != nil {
+					prev = n.renames.follow(lastStats, stats)
+				}
+				// 最后一次采样的间隔不完整, 按实际经过的时间计算
+				timing := sampleTiming{elapsed: interval, skew: skew, read: took, prev: lastRead, at: readAt}
+				if timing.measured, _ = monotonicElapsed(lastRead, readAt); timing.measured > 0 {
+					timing.elapsed = timing.measured
+				}
+				data = n.sample(prev, stats, timing)
+			}
+			if n.args.OnFinish != nil {
+				n.panicGuard().run("finish callback", func() { n.args.OnFinish(data) })
+			}
+			n.stop()
+			return
+		case reply := <-n.flushes:
+			begin := time.Now()
+			stats, skew, err := n.readNetDev()
+			took := time.Since(begin)
+			readAt := n.readTime()
+			if err != nil || firstIteration {
+				reply <- flushResult{}
+				continue
+			}
+			n.seen.record(stats)
+			prev := lastStats
+			if n.renames != nil {
+				prev = n.renames.follow(lastStats, stats)
+			}
+			timing := sampleTiming{elapsed: interval, skew: skew, read: took, prev: lastRead, at: readAt}
+			timing.measured, _ = monotonicElapsed(lastRead, readAt)
+			if gap := readAt.Sub(lastRead); timing.measured > 0 {
+				timing.elapsed = timing.measured
+			} else if gap > 0 {
+				timing.elapsed = gap
+			}
+			reply <- flushResult{data: n.sample(prev, stats, timing), ok: true}
+			lastStats, lastRead, lastLate = stats, readAt, false
+			resized = true // 下一次定时读取与本次读取之间不是 interval
+		case apply := <-n.reconfig:
+			prev := n.args.Interval
+			apply()
+			if n.adaptive == nil && n.args.Interval != prev {
+				interval = n.args.Interval
+				ticker.Reset(n.nextTick(interval))
+				resized = true
+			}
+		case tick := <-ticker.C:
+			// 回调等耗时超过间隔时, 定时器积压的触发会在回调结束后立即读取, 实际间隔与 interval 不符
+			late := time.Since(tick) > interval/10
+			if n.args.AlignedTicks {
+				ticker.Reset(n.nextTick(interval)) // 每次重新对齐, 时钟跳变后也能回到整点
+			}
+			if n.args.Gate != nil && !n.args.Gate() {
+				firstIteration = true // 暂停期间不读取, 恢复后重新读取基线
+				n.last = nil          // 不在暂停前后的采样之间插值
+				continue
+			}
+			begin := time.Now()
+			stats, skew, err := n.readNetDev() // 获取当前所有接口的数据
+			took := time.Since(begin)
+			readAt := n.readTime()
 			if err != nil {
-				mlog.Error(mlog.H{"error": err.Error()})
+				if n.args.PID > 0 && errors.Is(err, fs.ErrNotExist) {
+					if !n.args.Quiet {
+						mlog.Warn(mlog.H{"msg": "process exited, stop monitoring", "pid": n.args.PID})
+					}
+					n.fail(fmt.Errorf("pid %d: %w", n.args.PID, ErrProcessExited))
+					n.stop()
+					return
+				}
+				if !n.args.QuietErrors {
+					mlog.Error(mlog.H{"error": err.Error()})
+				}
+				readErrors++
+				if limit := n.args.MaxReadErrors; limit > 0 && readErrors >= limit {
+					if !n.args.QuietErrors {
+						mlog.Error(mlog.H{"msg": "too many consecutive read failures, stop monitoring", "count": readErrors})
+					}
+					n.fail(fmt.Errorf("%w %d times in a row: %w", ErrReadFailed, readErrors, err))
+					n.stop()
+					return
+				}
+				n.heartbeat(interval)
 				continue // 出错时继续下一次循环，而不是break
 			}
+			readErrors = 0
+			n.seen.record(stats)
+			wasResized := resized
+			resized = false
 
-			// 累加所有接口的接收和发送字节数
-			for _, v := range stats {
-				totalRx += v.Receive.Bytes
-				totalTx += v.Transmit.Bytes
+			prev := lastStats
+			if n.renames != nil {
+				prev = n.renames.follow(lastStats, stats)
 			}
-			if !firstIteration {
-				BytesRx := (totalRx - lastRx) / int64(n.args.Interval.Seconds())
-				BytesTx := (totalTx - lastTx) / int64(n.args.Interval.Seconds())
-
-				if BytesRx < 0 {
-					BytesRx = 0
+			if !firstIteration && warmup > 0 {
+				warmup-- // 预热期间只更新基线, 不回调
+			} else if !firstIteration {
+				timing := sampleTiming{elapsed: interval, skew: skew, read: took, prev: lastRead, at: readAt}
+				timing.measured, _ = monotonicElapsed(lastRead, readAt)
+				if (n.args.MonotonicRates || late || lastLate) && timing.measured > 0 {
+					timing.elapsed = timing.measured // 任一次读取推迟时按实际间隔计算, 回调的耗时不影响速率
 				}
+				gap := timing.measured
+				if gap == 0 {
+					gap = readAt.Sub(lastRead) // 没有单调时钟读数时按墙上时钟估计
+				}
+				if wasResized && gap > 0 {
+					timing.elapsed = gap // 与上一次读取之间既不是旧的也不是新的间隔
+				} else {
+					timing.missed = missedIntervals(gap, interval)
+				}
+				data := n.sample(prev, stats, timing)
 
-				if BytesTx < 0 {
-					BytesTx = 0
+				if n.adaptive != nil {
+					if next := n.adaptive.next(data.BytesRxF + data.BytesTxF); next != interval {
+						interval = next
+						ticker.Reset(n.nextTick(interval))
+						resized = true
+					}
 				}
-				n.args.Callback(TsCallData{
-					Name:       n.args.Name,
-					BytesTx:    BytesTx,
-					BytesRx:    BytesRx,
-					Interval:   n.args.Interval,
-					Interfaces: n.args.Interfaces,
-				})
 			} else {
 				firstIteration = false
+				if n.baseline != nil {
+					n.baseline.reset()
+				}
+				if !started && n.args.OnStart != nil {
+					n.panicGuard().run("start callback", func() { n.args.OnStart(slices.Sorted(maps.Keys(stats))) })
+				}
+				started = true
+				if n.tcp != nil {
+					n.tcp.ratio() // 与网络设备计数一起记录基线
+				}
+				n.heartbeat(interval)
 			}
 
-			// 更新上一次的接收和发送总字节
-			lastRx = totalRx
-			lastTx = totalTx
+			// 更新上一次的接口数据
+			lastStats = stats
+			lastRead = readAt
+			lastLate = late
 		}
 	}
 }
 
-func (n *netDev) readNetDev() (map[string]tsNetDev, error) {
-	file, err := os.Open(n.args.Path)
+// logSample 默认的回调, 输出到日志
+func (n *netDev) logSample(data TsCallData) {
+	if !n.args.Quiet {
+		mlog.Info(n.logFields(data))
+	}
+}
+
+func (n *netDev) logFields(data TsCallData) mlog.H {
+	fields := mlog.H{
+		"name":       data.Name,
+		"interfaces": data.Interfaces,
+		"interval":   data.Interval,
+
+		MetricBytesTx.String(): data.BytesTx,
+		MetricBytesRx.String(): data.BytesRx,
+	}
+	if n.args.PacketMetrics {
+		fields[MetricPacketsRx.String()] = data.PacketsRx
+		fields[MetricPacketsTx.String()] = data.PacketsTx
+	}
+	if n.args.ErrorMetrics {
+		fields["errs"] = data.ErrsRx + data.ErrsTx
+		fields["drop"] = data.DropRx + data.DropTx
+	}
+	return fields
+}
+
+// rates 按配置的指标和 bond 聚合方式计算两次读取之间的速率, 设置 WithMinInterfaceRate 时汇总不包括低于下限的接口
+func (n *netDev) rates(prev, cur map[string]TsNetDev, elapsed time.Duration) TsCallData {
+	data := n.diff(prev, cur, elapsed)
+	if floor := n.args.MinInterfaceRate; floor > 0 {
+		useFloorAggregate(&data, n.diff(prev, aboveFloor(prev, cur, elapsed, floor), elapsed))
+	}
+	return data
+}
+
+// diff 按配置的指标和 bond 聚合方式计算速率
+func (n *netDev) diff(prev, cur map[string]TsNetDev, elapsed time.Duration) TsCallData {
+	if n.args.BondMode != BondOff {
+		return diffBond(prev, cur, elapsed, n.args.Metrics, n.args.BondMode, n.args.SysfsRoot)
+	}
+	return diff(prev, cur, elapsed, n.args.Metrics)
+}
+
+// topTalker 找出收发速率之和最大的接口, 相同时取名称最小的; bond 成员不参与, 没有流量时为空
+func topTalker(data *TsCallData) {
+	var sat saturation
+	for name, r := range data.PerInterface {
+		if r.Bond != "" {
+			continue
+		}
+		total := sat.toInt(r.BytesRxF + r.BytesTxF)
+		if total > data.TopTalkerBytes || total > 0 && total == data.TopTalkerBytes && name < data.TopTalker {
+			data.TopTalker, data.TopTalkerBytes = name, total
+		}
+	}
+}
+
+// skipIdle 去掉累计收发字节数为 0 的接口
+func skipIdle(data *TsCallData, stats map[string]TsNetDev) {
+	idle := func(name string) bool {
+		s := stats[name]
+		return s.Receive.Bytes == 0 && s.Transmit.Bytes == 0
+	}
+	maps.DeleteFunc(data.PerInterface, func(name string, _ TsInterfaceRate) bool { return idle(name) })
+	if data.Interfaces != nil {
+		data.Interfaces = slices.DeleteFunc(slices.Clone(data.Interfaces), idle)
+	}
+}
+
+// clock 返回当前时间, 测试中可以通过 now 注入
+func (n *netDev) clock() time.Time {
+	if n.now != nil {
+		return n.now()
+	}
+	return time.Now()
+}
+
+// readTime 返回刚完成的读取的时间: WithSharedRead 复用的读取可能早于本次调用, 使用其实际读取的时间
+func (n *netDev) readTime() time.Time {
+	if at := n.sharedReadTime(); n.now == nil && !at.IsZero() {
+		return at
+	}
+	return n.clock()
+}
+
+// WithClock 用 now 代替 time.Now 记录每次读取的时间, 用于确定性测试, 例如与 MockSource 一起使用.
+// now 返回的时间没有单调时钟读数 (例如 time.Date 构造的时间) 时速率按采样间隔计算, 不受调度延迟影响
+func WithClock(now func() time.Time) netDevOpts {
+	return func(t *netDev) {
+		t.now = now
+	}
+}
+
+// missedIntervals 根据两次读取之间实际经过的时间计算错过的采样周期数
+func missedIntervals(gap, interval time.Duration) int64 {
+	if gap <= 0 || interval <= 0 {
+		return 0
+	}
+	return max(int64(gap/interval)-1, 0)
+}
+
+// nextTick 返回到下一次采样的时间, 对齐时为到下一个 interval 整数倍的时间
+func (n *netDev) nextTick(interval time.Duration) time.Duration {
+	if !n.args.AlignedTicks {
+		return interval
+	}
+	d := interval - time.Duration(n.clock().UnixNano()%int64(interval))
+	if d < interval/10 {
+		d += interval // 刚好在整点前醒来时跳到下一个整点, 避免连续两次采样
+	}
+	return d
+}
+
+// sampleTiming 一次采样的时间信息
+type sampleTiming struct {
+	elapsed  time.Duration // 计算速率使用的间隔
+	measured time.Duration // 两次读取之间的单调时钟间隔, 无法测量时为 0
+	skew     time.Duration // 多个文件读取时间的最大偏差
+	read     time.Duration // 读取并解析所有文件的耗时
+	missed   int64         // 两次读取之间错过的采样周期数
+	prev     time.Time     // 上一次读取的时间
+	at       time.Time     // 采样时间, 写入历史数据
+}
+
+// monotonicElapsed 计算两个时间之间的单调时钟间隔; 任一时间没有单调时钟读数时
+// (例如经过 Round(0) 或从墙上时钟重建) 减法会退回墙上时钟, 可能受 NTP 调整影响, 此时返回 false
+func monotonicElapsed(prev, cur time.Time) (time.Duration, bool) {
+	// == 会比较单调时钟读数, Round(0) 去掉单调时钟读数
+	if prev.IsZero() || prev == prev.Round(0) || cur == cur.Round(0) {
+		return 0, false
+	}
+	d := cur.Sub(prev)
+	return d, d > 0
+}
+
+// sample 计算两次读取之间的速率, 附加各选项的数据后交给回调和输出
+func (n *netDev) sample(prev, cur map[string]TsNetDev, timing sampleTiming) TsCallData {
+	elapsed, at := timing.elapsed, timing.at
+	read := cur // 过滤前的读取, 不是 up 的接口也要统计切换
+	if n.args.OnlyUp {
+		cur = n.onlyUp(cur) // 只过滤本次读取, 不是 up 的接口在 diff 中没有速率
+	}
+	data := n.rates(prev, cur, elapsed)
+	base, span := prev, elapsed
+	if n.baseline != nil {
+		base, span = n.baseline.push(prev, cur, elapsed)
+		useRates(&data, n.rates(base, cur, span))
+	}
+	data.Name = n.args.Name
+	data.Interfaces = n.args.Interfaces
+	data.ReadSkew = timing.skew
+	data.ReadDuration = timing.read
+	data.MonotonicElapsed = timing.measured
+	data.MissedIntervals = timing.missed
+	data.PrevSampledAt, data.SampledAt = timing.prev, at
+	data.Labels = maps.Clone(n.args.Labels)
+	data.Heartbeat = n.args.Heartbeat && data.DeltaRx == 0 && data.DeltaTx == 0
+	if n.args.SkipIdle {
+		skipIdle(&data, cur)
+	}
+	topTalker(&data)
+	n.groupRates(&data, prev, base, cur, span)
+
+	if n.args.Metrics.Has(MetricAverage) {
+		var sat saturation
+		n.totals.rx, n.totals.tx = sat.add(n.totals.rx, data.DeltaRx), sat.add(n.totals.tx, data.DeltaTx)
+		n.totals.elapsed += elapsed
+		data.Saturated = data.Saturated || sat.saturated
+		data.AvgBytesRx, data.AvgBytesTx = perSecond(n.totals.rx, n.totals.elapsed), perSecond(n.totals.tx, n.totals.elapsed)
+	}
+
+	if n.tcp != nil {
+		data.RetransRatio = n.tcp.ratio()
+	}
+
+	n.summary.add(data)
+	n.normalize(&data)
+	n.deltaPercent(&data)
+	if n.smoother != nil {
+		n.smoother.apply(&data)
+	}
+	if n.pods != nil {
+		n.pods.apply(&data)
+	}
+	if n.types != nil {
+		n.types.apply(&data)
+	}
+	if n.details != nil {
+		n.details.apply(&data, at)
+	}
+	if n.classes != nil {
+		n.classes.apply(&data)
+	}
+	n.derive(&data)
+	suppressed := n.dedup != nil && n.dedup.suppress(data, at)
+	if !suppressed {
+		n.interpolate(data)
+		n.args.Callback(data)
+	}
+	if n.anomaly != nil {
+		n.anomaly.check(data)
+	}
+	for _, th := range n.thresholds {
+		th.check(data)
+	}
+	if n.spikes != nil {
+		n.spikes.check(prev, cur, elapsed, n.args.ErrorSpikeFloor)
+	}
+	if n.args.OnReset != nil {
+		n.checkResets(prev, cur)
+	}
+	if n.flaps != nil {
+		n.flaps.check(n.args.SysfsRoot, read, at, n.args.LinkFlapTransitions, n.args.LinkFlapWindow)
+	}
+	n.notifySystemd()
+	if n.history != nil {
+		n.history.add(at, data, data.DeltaRx, data.DeltaTx)
+	}
+	if n.metrics != nil {
+		n.metrics.add(at, data, cur, n.args.Exemplars)
+	}
+	if !suppressed {
+		n.writeSinks(data)
+		n.streams.publish(data)
+	}
+	return data
+}
+
+// heartbeat 没有可计算的速率时回调零速率的心跳, 需要 WithHeartbeat
+func (n *netDev) heartbeat(interval time.Duration) {
+	if !n.args.Heartbeat {
+		return
+	}
+	n.args.Callback(TsCallData{
+		Name:       n.args.Name,
+		Interval:   interval,
+		Interfaces: n.args.Interfaces,
+		Labels:     maps.Clone(n.args.Labels),
+		Heartbeat:  true,
+	})
+}
+
+// writeSinks 按 SinkPolicy 将采样写入所有输出
+func (n *netDev) writeSinks(data TsCallData) {
+	if n.args.SinkPolicy == SinkAllOrNothing {
+		n.writeSinksAtomic(data)
+		return
+	}
+	for _, s := range n.sinks {
+		if err := s.Write(data); err != nil {
+			n.sinkError(s, err)
+		}
+	}
+}
+
+// sinkError 报告一个输出的错误, 设置了 WithSinkErrorHandler 时交给它, 否则记录日志
+func (n *netDev) sinkError(s Sink, err error) {
+	if n.args.SinkErrorHandler != nil {
+		n.args.SinkErrorHandler(s, err)
+		return
+	}
+	if !n.args.QuietErrors {
+		mlog.Error(mlog.H{"error": err.Error(), "sink": fmt.Sprintf("%T", s)})
+	}
+}
+
+func (n *netDev) closeSinks() {
+	for _, s := range n.sinks {
+		if err := s.Close(); err != nil {
+			n.sinkError(s, err)
+		}
+	}
+}
+
+// notifySystemd 采样成功后通知 systemd
+func (n *netDev) notifySystemd() {
+	if !n.args.SystemdNotify {
+		return
+	}
+	if !n.sdReady {
+		if err := sdNotify("READY=1"); err != nil {
+			if !n.args.QuietErrors {
+				mlog.Error(mlog.H{"error": err.Error()})
+			}
+			return
+		}
+		n.sdReady = true
+	}
+	if err := sdNotify("WATCHDOG=1"); err != nil && !n.args.QuietErrors {
+		mlog.Error(mlog.H{"error": err.Error()})
+	}
+}
+
+// normalize 按除数计算归一化后的速率, 原始值保持不变; 与引入浮点速率之前一样使用整数速率
+func (n *netDev) normalize(data *TsCallData) {
+	if n.args.Normalize == nil {
+		return
+	}
+	divisor := n.args.Normalize()
+	if divisor <= 0 {
+		return
+	}
+	data.NormalizedRx = float64(data.BytesRx) / divisor
+	data.NormalizedTx = float64(data.BytesTx) / divisor
+}
+
+// readNetDev 读取所有配置的文件并合并, 同时返回多个文件读取时间的最大偏差
+func (n *netDev) readNetDev() (map[string]TsNetDev, time.Duration, error) {
+	if n.source != nil {
+		stats, err := n.source.Read()
+		if err != nil {
+			return nil, 0, err
+		}
+		if n.args.NameResolver != nil {
+			return n.resolveNames(stats), 0, nil
+		}
+		if n.args.Interfaces != nil {
+			filter := n.args.filter()
+			maps.DeleteFunc(stats, func(name string, _ TsNetDev) bool { return !filter(name) })
+		}
+		return stats, 0, nil
+	}
+
+	paths := n.args.Paths
+	if len(paths) == 0 {
+		paths = []string{n.args.Path}
+	}
+
+	readers := make([]func() netDevRead, len(paths))
+	for i, path := range paths {
+		readers[i] = n.reader(path)
+	}
+	reads := make([]netDevRead, len(paths))
+	if n.args.ConcurrentRead && len(paths) > 1 {
+		var wg sync.WaitGroup
+		for i, read := range readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				reads[i] = read()
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i, read := range readers {
+			reads[i] = read()
+		}
+	}
+
+	items := make(map[string]TsNetDev)
+	skipped := make(map[string]bool)
+	defer func() { n.skipped = skipped }()
+	first, last := reads[0].at, reads[0].at
+	for i, r := range reads {
+		if r.err != nil {
+			return nil, 0, r.err
+		}
+		stats, err := n.parseNetDev(bytes.NewReader(r.data), func(pe *ParseError) {
+			pe.Path = paths[i]
+			n.warnSkipped(pe, skipped)
+		})
+		if err != nil {
+			if pe, ok := err.(*ParseError); ok {
+				pe.Path = paths[i]
+			}
+			return nil, 0, err
+		}
+		if err := n.mergeStats(items, stats, i); err != nil {
+			return nil, 0, err
+		}
+		if r.at.Before(first) {
+			first = r.at
+		}
+		if r.at.After(last) {
+			last = r.at
+		}
+	}
+	return items, last.Sub(first), nil
+}
+
+// netDevRead 一次文件读取的结果
+type netDevRead struct {
+	data []byte
+	at   time.Time // 读取完成的时间
+	err  error
+}
+
+func readFile(path string) netDevRead {
+	data, err := os.ReadFile(path)
+	return netDevRead{data: data, at: time.Now(), err: err}
+}
+
+// maxNetDevLine 单行的最大长度, 缓冲区按需从 4KB 增长, 避免超过 bufio.MaxScanTokenSize 的行导致整个读取失败
+const maxNetDevLine = 64 << 20
+
+// netDevFields 每行接口名之后应包含的字段数: 8 个接收字段 + 8 个发送字段
+const netDevFields = 16
+
+// Filter 决定是否保留某个接口, 为 nil 时保留所有接口
+type Filter func(iface string) bool
+
+// filter 返回按 Interfaces 过滤的 Filter, 没有设置 Interfaces 时返回 nil
+func (a *netDevArgs) filter() Filter {
+	if a.Interfaces == nil {
+		return nil
+	}
+	set := a.interfaces
+	if set == nil {
+		set = interfaceSet(a.Interfaces) // 没有通过 WithInterfaces 设置时
+	}
+	return func(iface string) bool {
+		_, ok := set[iface]
+		return ok
+	}
+}
+
+func interfaceSet(ifaces []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ifaces))
+	for _, iface := range ifaces {
+		set[iface] = struct{}{}
+	}
+	return set
+}
+
+// warnSkipped 记录被跳过的行, 上一次读取中没有的行输出警告; 一个坏行不影响其他接口的采样
+func (n *netDev) warnSkipped(pe *ParseError, skipped map[string]bool) {
+	key := pe.Error()
+	skipped[key] = true
+	if !n.skipped[key] && !n.args.Quiet {
+		mlog.Warn(mlog.H{"msg": "malformed interface line skipped", "error": key})
+	}
+}
+
+// parseNetDev 按监控的接口列表解析, 设置 NameResolver 时先映射接口名再过滤; skip 不为 nil 时接收被跳过的行
+func (n *netDev) parseNetDev(r io.Reader, skip func(*ParseError)) (map[string]TsNetDev, error) {
+	if n.args.NameResolver == nil {
+		return parse(r, n.args.filter(), n.args.HeaderMapping, skip)
+	}
+	stats, err := parse(r, nil, n.args.HeaderMapping, skip)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return n.resolveNames(stats), nil
+}
+
+// Parse 从 io.Reader 中解析 /proc/net/dev 格式的数据, 不启动协程也不记录日志.
+// 字段不足的接口行被跳过; 负数或非法的计数按 0 处理, 末尾没有换行的不完整行被忽略;
+// 读取出错 (例如行超过长度限制) 时返回 *ParseError
+func Parse(r io.Reader, filter Filter) (map[string]TsNetDev, error) {
+	return parse(r, filter, false, nil)
+}
 
-	items := make(map[string]tsNetDev)
-	scanner := bufio.NewScanner(file)
+// parse headerMapping 为 true 时按表头中的列名确定字段顺序, 没有可识别的表头时按位置解析;
+// 字段不足的行被跳过, skip 不为 nil 时以 *ParseError 接收这些行
+func parse(r io.Reader, filter Filter, headerMapping bool, skip func(*ParseError)) (map[string]TsNetDev, error) {
+	items := make(map[string]TsNetDev)
+	var columns netDevColumns
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxNetDevLine)
+	scanner.Split(scanCompleteLines)
+	lineno := 0
 	for scanner.Scan() {
+		lineno++
 		line := scanner.Text()
+		if isNetDevHeader(line) {
+			if headerMapping {
+				if c, ok := parseNetDevColumns(line); ok {
+					columns = c
+				}
+			}
+			continue
+		}
 		if !strings.Contains(line, ":") {
 			continue
 		}
 
-		fields := strings.Fields(line)
-		ifname := strings.Trim(fields[0], ":")
-
-		if n.args.Interfaces != nil {
-			if !slices.Contains(n.args.Interfaces, ifname) {
+		if columns != nil {
+			iface, ok := columns.parse(line)
+			if !ok {
+				if skip != nil {
+					skip(&ParseError{Line: lineno, Raw: line, Err: ErrTooFewFields})
+				}
 				continue
 			}
+			if filter == nil || filter(iface.Name) {
+				items[iface.Name] = iface
+			}
+			continue
 		}
 
-		count := mfunc.NewCounter(1)
-		iface := tsNetDev{
+		// 接口名是第一个冒号之前去掉首尾空白的部分, 其中可以有点号或空格;
+		// 有些内核在计数较大时冒号后没有空格 (eth0:123), 因此不能直接按空白拆分
+		name, rest, _ := strings.Cut(line, ":")
+		ifname := strings.TrimSpace(name)
+		fields := strings.Fields(rest)
+		if len(fields) < netDevFields {
+			if skip != nil {
+				skip(&ParseError{Line: lineno, Raw: line, Err: ErrTooFewFields})
+			}
+			continue // 字段不足的行视为格式错误, 跳过以免越界
+		}
+
+		if filter != nil && !filter(ifname) {
+			continue
+		}
+
+		count := mfunc.NewCounter(0)
+		iface := TsNetDev{
 			Name: ifname,
-			Receive: tsNetDevInfo{
-				Bytes:      mto.Int64(fields[count()]),
-				Packets:    mto.Int64(fields[count()]),
-				Errs:       mto.Int64(fields[count()]),
-				Drop:       mto.Int64(fields[count()]),
-				FIFO:       mto.Int64(fields[count()]),
-				Frame:      mto.Int64(fields[count()]),
-				Compressed: mto.Int64(fields[count()]),
-				Multicast:  mto.Int64(fields[count()]),
+			Receive: TsNetDevInfo{
+				Bytes:      toCounter(fields[count()]),
+				Packets:    toCounter(fields[count()]),
+				Errs:       toCounter(fields[count()]),
+				Drop:       toCounter(fields[count()]),
+				FIFO:       toCounter(fields[count()]),
+				Frame:      toCounter(fields[count()]),
+				Compressed: toCounter(fields[count()]),
+				Multicast:  toCounter(fields[count()]),
 			},
-			Transmit: tsNetDevInfo{
-				Bytes:      mto.Int64(fields[count()]),
-				Packets:    mto.Int64(fields[count()]),
-				Errs:       mto.Int64(fields[count()]),
-				Drop:       mto.Int64(fields[count()]),
-				FIFO:       mto.Int64(fields[count()]),
-				Colls:      mto.Int64(fields[count()]),
-				Carrier:    mto.Int64(fields[count()]),
-				Compressed: mto.Int64(fields[count()]),
+			Transmit: TsNetDevInfo{
+				Bytes:      toCounter(fields[count()]),
+				Packets:    toCounter(fields[count()]),
+				Errs:       toCounter(fields[count()]),
+				Drop:       toCounter(fields[count()]),
+				FIFO:       toCounter(fields[count()]),
+				Colls:      toCounter(fields[count()]),
+				Carrier:    toCounter(fields[count()]),
+				Compressed: toCounter(fields[count()]),
 			},
 		}
 		items[ifname] = iface
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		// 出错的是已读取的最后一行的下一行, 内容没有读出
+		return nil, &ParseError{Line: lineno + 1, Err: err}
 	}
 	return items, nil
 }
 
+// isNetDevHeader 判断是否为 /proc/net/dev 开头的两行表头 ("Inter-|..." 和 " face |..."),
+// 有些内核的表头中也带有冒号, 不能只靠冒号区分
+func isNetDevHeader(line string) bool {
+	marker, _, ok := strings.Cut(line, "|")
+	if !ok {
+		return false
+	}
+	marker = strings.TrimSpace(marker)
+	return marker == "Inter-" || marker == "face"
+}
+
+// toCounter 将字段转换为计数器值, 非法或负数的值按 0 处理
+func toCounter(field string) int64 {
+	v := mto.Int64(field)
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
 type TsCallData struct {
 	BytesTx int64
 	BytesRx int64
 
+	BytesTxF float64 // 未截断的发送速率
+	BytesRxF float64 // 未截断的接收速率
+
+	DeltaTx int64 // 区间内发送的字节数
+	DeltaRx int64 // 区间内接收的字节数
+
+	AvgBytesTx float64 // 启动以来的平均发送速率
+	AvgBytesRx float64 // 启动以来的平均接收速率
+
+	PacketsTx int64 // 每秒发送的包数
+	PacketsRx int64 // 每秒接收的包数
+	ErrsTx    int64 // 每秒发送错误数
+	ErrsRx    int64 // 每秒接收错误数
+	DropTx    int64 // 每秒发送丢包数
+	DropRx    int64 // 每秒接收丢包数
+
+	NormalizedTx float64 // 归一化后的发送速率, 需要 WithNormalize
+	NormalizedRx float64 // 归一化后的接收速率, 需要 WithNormalize
+
+	DeltaPercentRx float64 // 接收速率相对上一次采样的变化百分比, 上一次为 0 时为 DeltaPercentFromZero
+	DeltaPercentTx float64 // 发送速率相对上一次采样的变化百分比, 上一次为 0 时为 DeltaPercentFromZero
+
 	Interval   time.Duration
 	Interfaces []string
+	ReadSkew   time.Duration // 多个文件读取时间的最大偏差
+
+	ReadDuration time.Duration // 本次读取并解析 /proc 文件的耗时, 内存压力等导致读取变慢时增大
+
+	MonotonicElapsed time.Duration // 两次读取之间实际经过的单调时钟时间, 无法测量时为 0
+	MissedIntervals  int64         // 与上一次读取之间错过的采样周期数, 负载过高或系统休眠时大于 0
+
+	PrevSampledAt time.Time // 速率区间的起点, 即上一次读取的时间
+	SampledAt     time.Time // 速率区间的终点, 即本次读取的时间; 间隔不规则时用 [PrevSampledAt, SampledAt] 归属速率
+
+	SmoothedTx float64 // 平滑后的发送速率, 需要 WithSmoothing
+	SmoothedRx float64 // 平滑后的接收速率, 需要 WithSmoothing
+
+	UnfilteredBytesRx int64 // 包括低于 WithMinInterfaceRate 下限的接口的接收速率, 需要 WithMinInterfaceRate
+	UnfilteredBytesTx int64 // 包括低于 WithMinInterfaceRate 下限的接口的发送速率, 需要 WithMinInterfaceRate
+
+	PerInterface map[string]TsInterfaceRate // 每个接口的速率, 键为接口名
+	Groups       map[string]TsInterfaceRate // 每个接口组的速率之和, 键为组名, 需要 WithGroup
+
+	TopTalker      string // 收发速率之和最大的接口, 相同时取名称最小的; 需要 MetricPerInterface, 没有流量时为空
+	TopTalkerBytes int64  // TopTalker 每秒收发的字节数之和
+
+	Heartbeat bool // 零速率的心跳, 需要 WithHeartbeat
+	Saturated bool // 有值超出 int64, 已截断为 math.MaxInt64
+
+	Interpolated bool // 由相邻两次真实采样线性插值得到的估计值, 不是测量值, 需要 WithInterpolation
+
+	RetransRatio float64 // 区间内 TCP 重传报文段占发送报文段的比例, 需要 WithRetransRatio
+
+	Derived map[string]float64 // 派生指标的值, 键为指标名, 需要 WithDerived
+
+	Labels map[string]string // 采样的标签, 默认包含 hostname, 见 WithLabels
 
 	Name string
 }
 
-type tsNetDev struct {
+// TsInterfaceRate 单个接口的速率
+type TsInterfaceRate struct {
+	BytesTx int64
+	BytesRx int64
+
+	BytesTxF float64 // 未截断的发送速率
+	BytesRxF float64 // 未截断的接收速率
+
+	DeltaTx int64 // 区间内发送的字节数
+	DeltaRx int64 // 区间内接收的字节数
+
+	SmoothedTx float64 // 平滑后的发送速率, 需要 WithSmoothing
+	SmoothedRx float64 // 平滑后的接收速率, 需要 WithSmoothing
+
+	Pod       string // veth 接口所属的 Pod, 需要 WithPodResolver
+	Namespace string // Pod 所在的命名空间, 需要 WithPodResolver
+
+	Type string // 接口类型, 例如 InterfaceEthernet, 需要 WithInterfaceTypes
+
+	MTU      int // 需要 WithInterfaceDetails
+	TxQueues int // 发送队列数, 需要 WithInterfaceDetails
+	RxQueues int // 接收队列数, 需要 WithInterfaceDetails
+
+	Classes map[string]TsClassRate // 各流量类的速率, 需要 WithTrafficClasses
+
+	Bond string // 成员所属的 bond 接口, 不计入汇总, 需要 WithBondAggregation(BondBoth)
+}
+
+// TsNetDev 单个接口的计数器
+type TsNetDev struct {
 	Name     string       `json:"name"`
-	Transmit tsNetDevInfo `json:"transmit"`
-	Receive  tsNetDevInfo `json:"receive"`
+	Transmit TsNetDevInfo `json:"transmit"`
+	Receive  TsNetDevInfo `json:"receive"`
 }
 
-type tsNetDevInfo struct {
+// TsNetDevInfo 单个方向的计数器, 部分字段只在接收或发送方向存在
+type TsNetDevInfo struct {
 	Bytes      int64 `json:"bytes"`
 	Packets    int64 `json:"packets"`
 	Errs       int64 `json:"errs"`
@@ -0,0 +1,640 @@
+package mproc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewNetDevForPID(t *testing.T) {
+	n, err := NewNetDevForPID(4242, "pid", time.Second, WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	if n.args.Path != "/proc/4242/net/dev" || n.args.PID != 4242 {
+		t.Fatalf("Path = %q, PID = %d; want /proc/4242/net/dev, 4242", n.args.Path, n.args.PID)
+	}
+}
+
+// 进程退出后文件消失, 监控自动停止
+func TestNewNetDevForPIDStopsWhenGone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "4242", "net", "dev")
+	n, err := NewNetDevForPID(4242, "pid", time.Second, WithPath(path), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	select {
+	case <-n.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor kept running after /proc/<pid>/net/dev disappeared")
+	}
+	n.Close() // 重复关闭是安全的
+}
+
+func TestFloatRates(t *testing.T) {
+	n := newFIFONetDev(t, 2*time.Second, WithNormalize(func() float64 { return 4 }))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 3, 1))
+	data := n.next()
+	if data.BytesRx != 1 || data.BytesTx != 0 {
+		t.Fatalf("int rates = %d/%d, want the truncated 1/0", data.BytesRx, data.BytesTx)
+	}
+	if data.BytesRxF != 1.5 || data.BytesTxF != 0.5 {
+		t.Fatalf("float rates = %v/%v, want 1.5/0.5", data.BytesRxF, data.BytesTxF)
+	}
+	if data.NormalizedRx != 1.0/4 {
+		t.Fatalf("NormalizedRx = %v, want %v from the int rate", data.NormalizedRx, 1.0/4)
+	}
+}
+
+// 小于 1 秒的间隔按比例换算为每秒速率
+func TestSubSecondInterval(t *testing.T) {
+	n := newFIFONetDev(t, 500*time.Millisecond)
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	if data := n.next(); data.BytesRx != 2000 {
+		t.Fatalf("BytesRx = %d, want 2000", data.BytesRx)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	n := newFIFONetDev(t, time.Second, WithHeartbeat(true))
+	n.feed(netDevFile("eth0", 0, 0))
+	if data := n.next(); !data.Heartbeat {
+		t.Fatalf("baseline read: %+v, want a heartbeat", data)
+	}
+	n.feed(netDevFile("eth0", 0, 0))
+	if data := n.next(); !data.Heartbeat || data.BytesRx != 0 {
+		t.Fatalf("idle sample: %+v, want a zero-rate heartbeat", data)
+	}
+	n.feed(netDevFile("eth0", 1000, 0))
+	if data := n.next(); data.Heartbeat || data.BytesRx != 1000 {
+		t.Fatalf("busy sample: %+v, want BytesRx 1000 without heartbeat", data)
+	}
+}
+
+// 表头中带冒号时也不会被解析为接口
+func TestParseNetDevSkipsHeader(t *testing.T) {
+	content := `Inter-|   Receive                                                |  Transmit
+ face |bytes:   packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+  eth0: 1215645   2751    0    0    0     0          0         0  1782404   4324    0    0    0   427       0          0
+`
+	stats, err := Parse(strings.NewReader(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats["eth0"].Receive.Bytes != 1215645 {
+		t.Fatalf("stats = %+v, want only eth0", stats)
+	}
+}
+
+func TestWithLabels(t *testing.T) {
+	hostname, _ := os.Hostname()
+	n := newFIFONetDev(t, time.Second, WithLabels(map[string]string{"dc": "sh1"}))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	data := n.next()
+	if data.Labels["dc"] != "sh1" || data.Labels["hostname"] != hostname {
+		t.Fatalf("Labels = %v, want dc=sh1 and hostname=%s", data.Labels, hostname)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct{ Labels map[string]string }
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Labels["dc"] != "sh1" || decoded.Labels["hostname"] != hostname {
+		t.Fatalf("JSON labels = %s", b)
+	}
+}
+
+// 亲和性无法直接断言, 只检查采样不受影响
+func TestWithLockOSThread(t *testing.T) {
+	n := newFIFONetDev(t, time.Second, WithLockOSThread(true))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	if data := n.next(); data.BytesRx != 1000 {
+		t.Fatalf("BytesRx = %d, want 1000", data.BytesRx)
+	}
+}
+
+func TestAvgBytes(t *testing.T) {
+	n := newFIFONetDev(t, time.Second)
+	n.feed(netDevFile("eth0", 0, 0))
+	for i, want := range []float64{1000, 2000, 2000} {
+		n.feed(netDevFile("eth0", []int{1000, 4000, 6000}[i], 0))
+		if data := n.next(); data.AvgBytesRx != want {
+			t.Fatalf("sample %d: AvgBytesRx = %v, want %v", i, data.AvgBytesRx, want)
+		}
+	}
+}
+
+func TestSkipIdle(t *testing.T) {
+	n := newFIFONetDev(t, time.Second, WithSkipIdle(true))
+	n.feed(netDevFile("eth0", 0, 0, "eth1", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0, "eth1", 0, 0))
+	if data := n.next(); len(data.PerInterface) != 1 {
+		t.Fatalf("PerInterface = %v, want eth1 skipped while idle", data.PerInterface)
+	}
+	n.feed(netDevFile("eth0", 1000, 0, "eth1", 0, 500))
+	if data := n.next(); data.PerInterface["eth1"].BytesTx != 500 {
+		t.Fatalf("PerInterface = %v, want eth1 reported once active", data.PerInterface)
+	}
+}
+
+func TestTopTalker(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	prev := snapshot("eth0", 0, 0, "eth1", 0, 0, "eth2", 0, 0, "lo", 0, 0)
+	cases := []struct {
+		cur   map[string]TsNetDev
+		name  string
+		bytes int64
+	}{
+		{snapshot("eth0", 300, 100, "eth1", 100, 500, "eth2", 50, 50, "lo", 0, 0), "eth1", 600},
+		{snapshot("eth0", 300, 300, "eth1", 100, 500, "eth2", 0, 600, "lo", 0, 0), "eth0", 600}, // 相同时取名称最小的
+		{prev, "", 0}, // 没有流量
+	}
+	for i, c := range cases {
+		data := n.sample(prev, c.cur, sampleTiming{elapsed: time.Second})
+		if data.TopTalker != c.name || data.TopTalkerBytes != c.bytes {
+			t.Errorf("case %d: top talker = %q %d, want %q %d", i, data.TopTalker, data.TopTalkerBytes, c.name, c.bytes)
+		}
+	}
+}
+
+func TestNewNetDevForPIDInvalid(t *testing.T) {
+	if _, err := NewNetDevForPID(0, "pid", time.Second); err == nil {
+		t.Fatal("NewNetDevForPID(0) succeeded")
+	}
+}
+
+func TestNewNetDevInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second, time.Millisecond} {
+		if _, err := NewNetDev("test", interval); err == nil {
+			t.Fatalf("NewNetDev(%v) succeeded", interval)
+		}
+	}
+	n, err := NewNetDev("test", MinInterval, WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatalf("NewNetDev(%v): %v", MinInterval, err)
+	}
+	n.Close()
+}
+
+func TestConcurrentReadSkew(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	writeFile(t, a, netDevFile("eth0", 1000, 0))
+	writeFile(t, b, netDevFile("eth1", 2000, 0))
+
+	n := &netDev{args: &netDevArgs{Paths: []string{a, b}, ConcurrentRead: true}}
+	stats, skew, err := n.readNetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["eth0"].Receive.Bytes != 1000 || stats["eth1"].Receive.Bytes != 2000 {
+		t.Fatalf("stats = %+v, want eth0 and eth1 merged from both paths", stats)
+	}
+	if skew < 0 || skew > time.Second {
+		t.Fatalf("ReadSkew = %v, want a small non-negative duration", skew)
+	}
+
+	// 只有一个文件时没有偏差
+	single := &netDev{args: &netDevArgs{Path: a}}
+	if _, skew, err := single.readNetDev(); err != nil || skew != 0 {
+		t.Fatalf("single path: skew = %v, err = %v; want 0, nil", skew, err)
+	}
+}
+
+// slowSource 每次读取前等待 delay, 模拟 /proc 读取变慢
+type slowSource struct {
+	*MockSource
+	delay time.Duration
+}
+
+func (s slowSource) Read() (map[string]TsNetDev, error) {
+	time.Sleep(s.delay)
+	return s.MockSource.Read()
+}
+
+func TestReadDuration(t *testing.T) {
+	source := slowSource{
+		MockSource: NewMockSource(snapshot("eth0", 0, 0), snapshot("eth0", 1000, 0), snapshot("eth0", 2000, 0)),
+		delay:      5 * time.Millisecond,
+	}
+	got := make(chan TsCallData, 4)
+	n, err := NewNetDev("read", 20*time.Millisecond, WithStatsSource(source), WithCallback(func(d TsCallData) {
+		select {
+		case got <- d:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	select {
+	case data := <-got:
+		if data.ReadDuration < source.delay || data.ReadDuration > 5*time.Second {
+			t.Fatalf("ReadDuration = %v, want at least %v", data.ReadDuration, source.delay)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no sample")
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	data := TsCallData{BytesRx: 1, PacketsRx: 2, PacketsTx: 3, ErrsRx: 4, ErrsTx: 5, DropRx: 6, DropTx: 7}
+	n := &netDev{args: &netDevArgs{}}
+	if fields := n.logFields(data); fields["packets_rx"] != nil || fields["errs"] != nil {
+		t.Fatalf("fields = %v, want bytes only by default", fields)
+	}
+
+	n.args.PacketMetrics, n.args.ErrorMetrics = true, true
+	fields := n.logFields(data)
+	for key, want := range map[string]int64{"bytes_rx": 1, "packets_rx": 2, "packets_tx": 3, "errs": 9, "drop": 13} {
+		if fields[key] != want {
+			t.Fatalf("fields[%s] = %v, want %d", key, fields[key], want)
+		}
+	}
+}
+
+func TestNextTickAligned(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 3, 0, time.UTC)
+	now := base
+	n := &netDev{args: &netDevArgs{AlignedTicks: true}, now: func() time.Time { return now }}
+	for _, c := range []struct {
+		offset time.Duration
+		want   time.Duration
+	}{
+		{0, 7 * time.Second},                // 12:00:03 -> 12:00:10
+		{7 * time.Second, 10 * time.Second}, // 正好在整点 -> 下一个整点
+		{6*time.Second + 950*time.Millisecond, 10*time.Second + 50*time.Millisecond}, // 整点前醒来
+		{time.Hour + 5*time.Second, 2 * time.Second},                                 // 时钟跳变后重新对齐
+	} {
+		now = base.Add(c.offset)
+		if got := n.nextTick(10 * time.Second); got != c.want {
+			t.Fatalf("at %v: nextTick = %v, want %v", now.Format(time.TimeOnly), got, c.want)
+		}
+		if at := now.Add(n.nextTick(10 * time.Second)); at.Second()%10 != 0 || at.Nanosecond() != 0 {
+			t.Fatalf("at %v: next sample at %v, want aligned to 10s", now, at)
+		}
+	}
+
+	n.args.AlignedTicks = false
+	if got := n.nextTick(10 * time.Second); got != 10*time.Second {
+		t.Fatalf("unaligned nextTick = %v, want the interval", got)
+	}
+}
+
+func TestMonotonicElapsed(t *testing.T) {
+	prev := time.Now()
+	cur := prev.Add(time.Second) // Add 同时调整墙上时钟和单调时钟
+	if d, ok := monotonicElapsed(prev, cur); !ok || d != time.Second {
+		t.Fatalf("monotonicElapsed = %v, %v; want 1s", d, ok)
+	}
+
+	// 从墙上时钟重建的时间在 NTP 向前调整 1 小时后, 不能用于计算间隔
+	jumped := cur.Round(0).Add(time.Hour)
+	if d, ok := monotonicElapsed(prev, jumped); ok {
+		t.Fatalf("monotonicElapsed across a wall-clock jump = %v, want rejected", d)
+	}
+	if _, ok := monotonicElapsed(time.Time{}, cur); ok {
+		t.Fatal("monotonicElapsed without a previous read succeeded")
+	}
+}
+
+func TestMonotonicRates(t *testing.T) {
+	n := newFIFONetDev(t, 500*time.Millisecond, WithMonotonicRates(true))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	data := n.next()
+	if data.MonotonicElapsed < 400*time.Millisecond || data.MonotonicElapsed > 5*time.Second {
+		t.Fatalf("MonotonicElapsed = %v, want about 500ms", data.MonotonicElapsed)
+	}
+	if want := 1000 / data.MonotonicElapsed.Seconds(); data.BytesRxF != want {
+		t.Fatalf("BytesRxF = %v, want %v from the measured elapsed time", data.BytesRxF, want)
+	}
+}
+
+func TestWithMaxLifetime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 0, 0))
+	finished := make(chan TsCallData, 1)
+	n, err := NewNetDev("test", 100*time.Millisecond, WithPath(path), WithCallback(func(TsCallData) {}),
+		WithMaxLifetime(350*time.Millisecond, func(data TsCallData) { finished <- data }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	select {
+	case data := <-finished:
+		if data.MonotonicElapsed <= 0 || data.Interval != data.MonotonicElapsed {
+			t.Fatalf("final sample = %+v, want the interval measured up to the deadline", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("onFinish not called")
+	}
+	select {
+	case <-n.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor kept running after its lifetime")
+	}
+}
+
+func TestMissedIntervals(t *testing.T) {
+	// 注入的时钟没有单调时钟读数, 每次读取依次前进 1 个、4.5 个 (错过 3 个) 和 1 个周期
+	const interval = 50 * time.Millisecond
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{0, interval, 5*interval + interval/2, 6*interval + interval/2}
+	var reads int
+	clock := func(t *netDev) {
+		t.now = func() time.Time {
+			at := base.Add(offsets[min(reads, len(offsets)-1)])
+			reads++
+			return at
+		}
+	}
+	n := newFIFONetDev(t, interval, clock)
+	n.feed(netDevFile("eth0", 0, 0))
+	for i, want := range []int64{0, 3, 0} {
+		n.feed(netDevFile("eth0", 1000*(i+1), 0))
+		if got := n.next().MissedIntervals; got != want {
+			t.Fatalf("sample %d: MissedIntervals = %d, want %d", i+1, got, want)
+		}
+	}
+}
+
+func TestSampledAtSpan(t *testing.T) {
+	n := newFIFONetDev(t, 50*time.Millisecond)
+	n.feed(netDevFile("eth0", 0, 0))
+	var last time.Time
+	for i := range 3 {
+		n.feed(netDevFile("eth0", 1000*(i+1), 0))
+		data := n.next()
+		if data.PrevSampledAt.IsZero() || !data.PrevSampledAt.Before(data.SampledAt) {
+			t.Fatalf("sample %d: span [%v, %v] not populated and ordered", i+1, data.PrevSampledAt, data.SampledAt)
+		}
+		// 相邻的区间首尾相接
+		if !last.IsZero() && !data.PrevSampledAt.Equal(last) {
+			t.Fatalf("sample %d: PrevSampledAt = %v, want previous SampledAt %v", i+1, data.PrevSampledAt, last)
+		}
+		last = data.SampledAt
+	}
+}
+
+func TestWithOnStart(t *testing.T) {
+	started := make(chan []string, 4)
+	n := newFIFONetDev(t, 50*time.Millisecond,
+		WithInterfaces("eth0", "eth1"),
+		WithOnStart(func(interfaces []string) { started <- interfaces }),
+	)
+	n.feed(netDevFile("lo", 0, 0, "eth1", 0, 0, "eth0", 0, 0))
+	n.feed(netDevFile("lo", 0, 0, "eth1", 0, 0, "eth0", 100, 0))
+	n.next()
+	n.feed(netDevFile("lo", 0, 0, "eth1", 0, 0, "eth0", 200, 0))
+	n.next()
+
+	if got := <-started; !slices.Equal(got, []string{"eth0", "eth1"}) {
+		t.Fatalf("OnStart interfaces = %q, want [eth0 eth1]", got)
+	}
+	if len(started) != 0 {
+		t.Fatalf("OnStart called %d more times, want once", len(started))
+	}
+}
+
+// rampSource 每次读取接收字节数增加 100, 另加 offset
+type rampSource struct{ reads, offset atomic.Int64 }
+
+func (s *rampSource) Read() (map[string]TsNetDev, error) {
+	rx := s.reads.Add(1)*100 + s.offset.Load()
+	return map[string]TsNetDev{"eth0": {Name: "eth0", Receive: TsNetDevInfo{Bytes: rx}}}, nil
+}
+
+func TestFlush(t *testing.T) {
+	idle, err := NewNetDev("idle", time.Hour, WithStatsSource(&rampSource{}), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idle.Flush(); ok {
+		t.Fatal("Flush before the baseline read returned ok")
+	}
+	idle.Close()
+	if _, ok := idle.Flush(); ok {
+		t.Fatal("Flush after Close returned ok")
+	}
+
+	samples := make(chan TsCallData, 16)
+	n, err := NewNetDev("flush", 200*time.Millisecond, WithStatsSource(&rampSource{}), WithCallback(func(data TsCallData) { samples <- data }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	<-samples
+
+	// 每次读取增加 100, 两次定时采样之间的 Flush 与下一次定时采样各计算 100
+	time.Sleep(50 * time.Millisecond)
+	data, ok := n.Flush()
+	if !ok || data.DeltaRx != 100 || data.Interval <= 0 || data.Interval >= 200*time.Millisecond {
+		t.Fatalf("Flush = %+v, %v; want DeltaRx 100 over less than the interval", data, ok)
+	}
+	if flushed := <-samples; flushed.DeltaRx != 100 {
+		t.Fatalf("callback got DeltaRx %d from Flush, want 100", flushed.DeltaRx)
+	}
+	if next := <-samples; next.DeltaRx != 100 || next.Interval >= 200*time.Millisecond {
+		t.Fatalf("next tick DeltaRx = %d over %v, want 100 since the flush", next.DeltaRx, next.Interval)
+	}
+}
+
+func TestWithGate(t *testing.T) {
+	var open atomic.Bool
+	open.Store(true)
+	source := &rampSource{}
+	samples := make(chan TsCallData, 64)
+	n, err := NewNetDev("gate", 10*time.Millisecond,
+		WithStatsSource(source),
+		WithGate(open.Load),
+		WithCallback(func(data TsCallData) {
+			select {
+			case samples <- data:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	expect := func(phase string) {
+		t.Helper()
+		for range 2 {
+			select {
+			case data := <-samples:
+				if data.DeltaRx != 100 {
+					t.Fatalf("%s: DeltaRx = %d, want 100", phase, data.DeltaRx)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: no sample", phase)
+			}
+		}
+	}
+	expect("before pause")
+
+	open.Store(false)
+	time.Sleep(30 * time.Millisecond) // 等待进行中的周期结束
+	reads := source.reads.Load()
+	time.Sleep(100 * time.Millisecond)
+	if got := source.reads.Load(); got != reads {
+		t.Fatalf("%d reads while the gate was closed, want 0", got-reads)
+	}
+	// 暂停期间的流量不应计入恢复后的第一个采样
+	source.offset.Store(1 << 20)
+	for len(samples) > 0 {
+		<-samples
+	}
+	open.Store(true)
+	expect("after resume")
+}
+
+func TestInterfaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, fixtureNetDev)
+	n, err := NewNetDev("test", time.Hour, WithPath(path), WithInterfaces("eth0", "eth9"), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 还没有采样, 在采样协程中读取; 配置中不存在的 eth9 不在结果中
+	if got := n.Interfaces(); !slices.Equal(got, []string{"eth0"}) {
+		t.Fatalf("Interfaces = %q, want [eth0]", got)
+	}
+	writeFile(t, path, netDevFile("eth0", 0, 0, "eth9", 0, 0))
+	if got := n.Interfaces(); !slices.Equal(got, []string{"eth0"}) {
+		t.Fatalf("Interfaces = %q, want the last read [eth0]", got)
+	}
+	n.Close()
+	if got := n.Interfaces(); !slices.Equal(got, []string{"eth0"}) {
+		t.Fatalf("Interfaces after Close = %q, want [eth0]", got)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	n := newFIFONetDev(t, 50*time.Millisecond, WithWarmup(2))
+	n.feed(netDevFile("eth0", 0, 0))     // 基线
+	n.feed(netDevFile("eth0", 9999, 0))  // 预热, 不回调
+	n.feed(netDevFile("eth0", 10000, 0)) // 预热, 不回调
+	n.feed(netDevFile("eth0", 10500, 0))
+	if data := n.next(); data.DeltaRx != 500 {
+		t.Fatalf("first callback DeltaRx = %d, want 500 after warmup", data.DeltaRx)
+	}
+	n.feed(netDevFile("eth0", 11000, 0))
+	if data := n.next(); data.DeltaRx != 500 {
+		t.Fatalf("second callback DeltaRx = %d, want 500", data.DeltaRx)
+	}
+}
+
+// clockSource 接收字节数随时间线性增长, 速率固定为 rate 字节/秒
+type clockSource struct {
+	start time.Time
+	rate  float64
+}
+
+func (s clockSource) Read() (map[string]TsNetDev, error) {
+	rx := int64(time.Since(s.start).Seconds() * s.rate)
+	return map[string]TsNetDev{"eth0": {Name: "eth0", Receive: TsNetDevInfo{Bytes: rx}}}, nil
+}
+
+// 间隔变化后的第一次采样按实际经过的时间计算速率, 而不是新的间隔
+func TestIntervalChangeUsesElapsed(t *testing.T) {
+	const rate = 1e6
+	samples := make(chan TsCallData, 16)
+	n, err := NewNetDev("test", 200*time.Millisecond,
+		WithStatsSource(clockSource{start: time.Now(), rate: rate}),
+		WithCallback(func(data TsCallData) { samples <- data }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	<-samples
+	// 距上一次读取约 150ms 时改为 50ms, 下一次读取距上一次约 200ms
+	time.Sleep(150 * time.Millisecond)
+	if err := n.Reconfigure(WithInterval(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	for len(samples) > 0 {
+		<-samples
+	}
+	data := <-samples
+	if data.BytesRxF < rate*0.8 || data.BytesRxF > rate*1.2 || data.Interval < 150*time.Millisecond {
+		t.Fatalf("BytesRxF = %.0f over %v, want about %.0f over the real elapsed time", data.BytesRxF, data.Interval, rate)
+	}
+}
+
+// 回调耗时超过间隔时定时器的触发被推迟, 速率仍按实际间隔计算
+func TestSlowCallback(t *testing.T) {
+	const rate = 1e6
+	samples := make(chan TsCallData, 16)
+	n, err := NewNetDev("test", 50*time.Millisecond,
+		WithStatsSource(clockSource{start: time.Now(), rate: rate}),
+		WithCallback(func(data TsCallData) {
+			time.Sleep(80 * time.Millisecond)
+			samples <- data
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	for i := range 4 {
+		data := <-samples
+		if data.BytesRxF < rate*0.8 || data.BytesRxF > rate*1.2 {
+			t.Fatalf("sample %d: BytesRxF = %.0f over %v, want about %.0f", i, data.BytesRxF, data.Interval, rate)
+		}
+	}
+}
+
+// procfs 和 sysfs 都在同一个根目录下, 例如容器中挂载的宿主机目录
+func TestWithProcRoot(t *testing.T) {
+	host := t.TempDir()
+	proc := filepath.Join(host, "proc")
+	writeFile(t, filepath.Join(proc, "net", "dev"), netDevFile("eth0", 1000, 2000, "eth1", 3000, 4000))
+	writeFile(t, filepath.Join(host, "sys", "class", "net", "eth0", "operstate"), "up\n")
+	writeFile(t, filepath.Join(host, "sys", "class", "net", "eth1", "operstate"), "down\n")
+
+	n, err := newNetDev("host", time.Second, WithProcRoot(proc+"/"), WithOnlyUp(true), WithRetransRatio(true), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.args.Path != filepath.Join(proc, "net", "dev") || n.args.SysfsRoot != filepath.Join(host, "sys") || n.tcp.path != filepath.Join(proc, "net", "snmp") {
+		t.Fatalf("paths = %s, %s, %s; want all under %s", n.args.Path, n.args.SysfsRoot, n.tcp.path, host)
+	}
+	stats, _, err := n.readNetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := n.sample(snapshot("eth0", 0, 0, "eth1", 0, 0), stats, sampleTiming{elapsed: time.Second})
+	if data.BytesRx != 1000 || len(data.PerInterface) != 1 {
+		t.Fatalf("BytesRx = %d, PerInterface = %v; want only eth0 from the fake tree", data.BytesRx, data.PerInterface)
+	}
+
+	pid, err := NewNetDevForPID(4242, "pid", time.Second, WithProcRoot(proc), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Close()
+	if want := filepath.Join(proc, "4242", "net", "dev"); pid.args.Path != want {
+		t.Fatalf("pid path = %s, want %s", pid.args.Path, want)
+	}
+}
@@ -0,0 +1,49 @@
+package mproc
+
+import "testing"
+
+// 除数在每次采样时重新获取, 例如链路速率发生变化; 原始速率保持不变
+func TestNormalize(t *testing.T) {
+	divisor := 8.0
+	n := &netDev{args: &netDevArgs{Normalize: func() float64 { return divisor }}}
+
+	data := TsCallData{BytesRx: 8000, BytesTx: 4000}
+	n.normalize(&data)
+	if data.BytesRx != 8000 || data.BytesTx != 4000 {
+		t.Fatalf("raw rates = %d/%d, want 8000/4000", data.BytesRx, data.BytesTx)
+	}
+	if data.NormalizedRx != 1000 || data.NormalizedTx != 500 {
+		t.Fatalf("Normalized = %v/%v, want 1000/500", data.NormalizedRx, data.NormalizedTx)
+	}
+
+	divisor = 4
+	data = TsCallData{BytesRx: 8000}
+	n.normalize(&data)
+	if data.NormalizedRx != 2000 {
+		t.Fatalf("after divisor change: NormalizedRx = %v, want 2000", data.NormalizedRx)
+	}
+}
+
+func TestNormalizeIgnoresInvalidDivisor(t *testing.T) {
+	for _, divisor := range []float64{0, -1} {
+		n := &netDev{args: &netDevArgs{Normalize: func() float64 { return divisor }}}
+		data := TsCallData{BytesRx: 1000, BytesTx: 1000}
+		n.normalize(&data)
+		if data.NormalizedRx != 0 || data.NormalizedTx != 0 {
+			t.Fatalf("Normalized = %v/%v, want 0 for divisor %v", data.NormalizedRx, data.NormalizedTx, divisor)
+		}
+	}
+}
+
+// 归一化沿用整数速率, 浮点速率不被修改
+func TestNormalizeFloatRates(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Normalize: func() float64 { return 4 }}}
+	data := TsCallData{BytesRx: 1, BytesRxF: 1.5, BytesTxF: 0.5}
+	n.normalize(&data)
+	if data.BytesRx != 1 || data.BytesRxF != 1.5 || data.BytesTxF != 0.5 {
+		t.Fatalf("raw rates = %d/%v/%v, want 1/1.5/0.5", data.BytesRx, data.BytesRxF, data.BytesTxF)
+	}
+	if data.NormalizedRx != 1.0/4 || data.NormalizedTx != 0 {
+		t.Fatalf("Normalized = %v/%v, want %v/0 from the int rates", data.NormalizedRx, data.NormalizedTx, 1.0/4)
+	}
+}
@@ -0,0 +1,156 @@
+package mproc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openMetricsContentType OpenMetrics 文本格式, 只有这个格式支持 exemplar
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// openMetricsSeries 一个接口的原始计数器和最近一次采样的速率
+type openMetricsSeries struct {
+	labels           [][2]string
+	counter          bool  // 是否输出计数器, 汇总速率没有对应的原始计数器
+	rawRx, rawTx     int64 // 内核中的原始累计字节数, 计数器重置由 Prometheus 识别
+	rate             bool  // 是否输出速率
+	rateRx, rateTx   float64
+	deltaRx, deltaTx int64 // 最近一次采样的增量, 作为 exemplar 的值
+	at               time.Time
+}
+
+// openMetrics 以 OpenMetrics 文本格式暴露最近的采样, 需要 WithOpenMetrics
+//
+// 计数器 (mproc_netdev_*_bytes_total) 直接输出内核中的原始累计值, 而不是本监控计算的增量之和:
+// 接口重建或重启后计数器变小, 由 Prometheus 的 rate()/increase() 按计数器重置处理.
+// 仪表 (mproc_netdev_*_bytes_per_second) 输出本监控按采样间隔计算的速率
+type openMetrics struct {
+	mu        sync.Mutex
+	exemplars bool
+	series    map[string]*openMetricsSeries
+}
+
+func newOpenMetrics() *openMetrics {
+	return &openMetrics{series: map[string]*openMetricsSeries{}}
+}
+
+// add 在采样协程中调用, cur 为本次读取的原始计数器; 消失的接口不再输出.
+// 没有 PerInterface 时各接口只输出计数器, 速率以不带 interface 标签的汇总输出
+func (m *openMetrics) add(at time.Time, data TsCallData, cur map[string]TsNetDev, exemplars bool) {
+	base := sampleLabels(data)
+	series := make(map[string]*openMetricsSeries, len(cur)+1)
+	for iface, c := range cur {
+		s := &openMetricsSeries{
+			labels:  withLabel(base, "interface", iface),
+			counter: true,
+			rawRx:   c.Receive.Bytes,
+			rawTx:   c.Transmit.Bytes,
+			at:      at,
+		}
+		if r, ok := data.PerInterface[iface]; ok {
+			s.rate, s.rateRx, s.rateTx = true, r.BytesRxF, r.BytesTxF
+			s.deltaRx, s.deltaTx = r.DeltaRx, r.DeltaTx
+		}
+		series[iface] = s
+	}
+	if data.PerInterface == nil {
+		series[""] = &openMetricsSeries{labels: base, rate: true, rateRx: data.BytesRxF, rateTx: data.BytesTxF, at: at}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exemplars = exemplars
+	m.series = series
+}
+
+// ServeHTTP 输出 OpenMetrics 文本, 启用 WithExemplars 时计数器附带最近一次采样的 exemplar
+func (m *openMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", openMetricsContentType)
+	m.write(w)
+}
+
+func (m *openMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	type family struct {
+		name, typ, help string
+		value           func(*openMetricsSeries) string
+		exemplar        func(*openMetricsSeries) int64
+	}
+	families := []family{
+		{"mproc_netdev_receive_bytes", "counter", "Raw cumulative bytes received, as reported by the kernel.",
+			func(s *openMetricsSeries) string { return strconv.FormatInt(s.rawRx, 10) },
+			func(s *openMetricsSeries) int64 { return s.deltaRx }},
+		{"mproc_netdev_transmit_bytes", "counter", "Raw cumulative bytes transmitted, as reported by the kernel.",
+			func(s *openMetricsSeries) string { return strconv.FormatInt(s.rawTx, 10) },
+			func(s *openMetricsSeries) int64 { return s.deltaTx }},
+		{"mproc_netdev_receive_bytes_per_second", "gauge", "Receive rate of the latest sample.",
+			func(s *openMetricsSeries) string { return strconv.FormatFloat(s.rateRx, 'f', -1, 64) }, nil},
+		{"mproc_netdev_transmit_bytes_per_second", "gauge", "Transmit rate of the latest sample.",
+			func(s *openMetricsSeries) string { return strconv.FormatFloat(s.rateTx, 'f', -1, 64) }, nil},
+	}
+	for _, f := range families {
+		fmt.Fprintf(w, "# TYPE %s %s\n# HELP %s %s\n", f.name, f.typ, f.name, f.help)
+		name := f.name
+		if f.typ == "counter" {
+			name += "_total"
+		}
+		for _, k := range keys {
+			s := m.series[k]
+			if (f.typ == "counter" && !s.counter) || (f.typ == "gauge" && !s.rate) {
+				continue
+			}
+			fmt.Fprintf(w, "%s%s %s", name, formatLabels(s.labels), f.value(s))
+			if m.exemplars && f.exemplar != nil && s.rate {
+				// exemplar 只带 interface 标签, 值为该次采样的增量, 时间戳为采样时间
+				var ex [][2]string
+				if k != "" {
+					ex = [][2]string{{"interface", k}}
+				}
+				fmt.Fprintf(w, " # %s %d %s", formatLabels(ex), f.exemplar(s), formatTimestamp(s.at))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w, "# EOF")
+}
+
+// formatLabels 输出 {a="1",b="2"}, 没有标签时 exemplar 仍需要 {}
+func formatLabels(pairs [][2]string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(p[0])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(p[1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+// formatTimestamp OpenMetrics 的时间戳为秒, 保留毫秒
+func formatTimestamp(at time.Time) string {
+	return strconv.FormatFloat(float64(at.UnixMilli())/1000, 'f', 3, 64)
+}
@@ -0,0 +1,123 @@
+package mproc
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenMetricsExemplars(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Name: "all", Callback: func(TsCallData) {}, Metrics: MetricAll}}
+	WithOpenMetrics()(n)
+	WithExemplars(true)(n)
+	at := time.Unix(1700000000, 123e6)
+	n.sample(snapshot("eth0", 0, 0), snapshot("eth0", 1000, 2000), sampleTiming{elapsed: time.Second, at: at})
+
+	rec := httptest.NewRecorder()
+	n.OpenMetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Fatalf("body does not end with # EOF:\n%s", body)
+	}
+	for _, want := range []string{
+		`mproc_netdev_receive_bytes_total{interface="eth0",name="all"} 1000 # {interface="eth0"} 1000 1700000000.123`,
+		`mproc_netdev_transmit_bytes_total{interface="eth0",name="all"} 2000 # {interface="eth0"} 2000 1700000000.123`,
+		`mproc_netdev_receive_bytes_per_second{interface="eth0",name="all"} 1000`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("missing line %q in:\n%s", want, body)
+		}
+	}
+
+	// 每行 exemplar 的格式: 值 # {标签} 值 时间戳
+	exemplar := regexp.MustCompile(`^\S+\{[^}]*\} \d+ # \{(\w+="[^"]*"(,\w+="[^"]*")*)?\} -?\d+(\.\d+)? \d+\.\d{3}$`)
+	var found int
+	for _, line := range strings.Split(body, "\n") {
+		if strings.Contains(line, " # ") {
+			found++
+			if !exemplar.MatchString(line) {
+				t.Errorf("malformed exemplar line %q", line)
+			}
+		}
+	}
+	if found != 2 {
+		t.Errorf("found %d exemplar lines, want 2", found)
+	}
+}
+
+func TestOpenMetricsWithoutExemplars(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Name: "all", Callback: func(TsCallData) {}, Metrics: MetricAll}}
+	WithOpenMetrics()(n)
+	n.sample(snapshot("eth0", 0, 0), snapshot("eth0", 1000, 2000), sampleTiming{elapsed: time.Second, at: time.Now()})
+	n.sample(snapshot("eth0", 1000, 2000), snapshot("eth0", 1500, 2000), sampleTiming{elapsed: time.Second, at: time.Now()})
+
+	var b strings.Builder
+	n.metrics.write(&b)
+	if strings.Contains(b.String(), " # ") {
+		t.Fatalf("unexpected exemplar:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), `mproc_netdev_receive_bytes_total{interface="eth0",name="all"} 1500`+"\n") {
+		t.Fatalf("counter not accumulated:\n%s", b.String())
+	}
+	if (&netDev{args: &netDevArgs{}}).OpenMetricsHandler() != nil {
+		t.Fatal("OpenMetricsHandler without WithOpenMetrics should be nil")
+	}
+}
+
+// 计数器输出内核的原始累计值, 重置后如实变小由 Prometheus 识别; 仪表输出计算的速率
+func TestOpenMetricsCounterReset(t *testing.T) {
+	n := &netDev{args: &netDevArgs{Name: "all", Callback: func(TsCallData) {}, Metrics: MetricAll}}
+	WithOpenMetrics()(n)
+	scrape := func() string {
+		var b strings.Builder
+		n.metrics.write(&b)
+		return b.String()
+	}
+
+	n.sample(snapshot("eth0", 5000, 0), snapshot("eth0", 9000, 0), sampleTiming{elapsed: time.Second, at: time.Now()})
+	body := scrape()
+	for _, want := range []string{
+		`mproc_netdev_receive_bytes_total{interface="eth0",name="all"} 9000`,
+		`mproc_netdev_receive_bytes_per_second{interface="eth0",name="all"} 4000`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+
+	// 接口重建, 计数器从 0 开始
+	n.sample(snapshot("eth0", 9000, 0), snapshot("eth0", 300, 0), sampleTiming{elapsed: time.Second, at: time.Now()})
+	body = scrape()
+	for _, want := range []string{
+		`mproc_netdev_receive_bytes_total{interface="eth0",name="all"} 300`,
+		`mproc_netdev_receive_bytes_per_second{interface="eth0",name="all"} 0`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Fatalf("after reset: missing %q in:\n%s", want, body)
+		}
+	}
+	if !strings.Contains(body, "# TYPE mproc_netdev_receive_bytes counter\n") || !strings.Contains(body, "# TYPE mproc_netdev_receive_bytes_per_second gauge\n") {
+		t.Fatalf("wrong metric types in:\n%s", body)
+	}
+
+	// 关闭 PerInterface 时各接口只有计数器, 速率以汇总输出
+	WithMetrics(MetricBytesRx, MetricBytesTx)(n)
+	n.sample(snapshot("eth0", 300, 0), snapshot("eth0", 800, 0), sampleTiming{elapsed: time.Second, at: time.Now()})
+	body = scrape()
+	for _, want := range []string{
+		`mproc_netdev_receive_bytes_total{interface="eth0",name="all"} 800`,
+		`mproc_netdev_receive_bytes_per_second{name="all"} 500`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Fatalf("aggregate: missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `_per_second{interface="eth0"`) {
+		t.Fatalf("aggregate: unexpected per-interface rate in:\n%s", body)
+	}
+}
@@ -0,0 +1,31 @@
+package mproc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithOnlyUp 只计算 sysfs 中 operstate 为 up 的接口, 其他接口不计入汇总, 也不出现在 PerInterface 中;
+// 接口变为 up 后重新计入. 每次采样读取 operstate, lo 和部分 tun 接口的 operstate 为 unknown, 同样不计入
+func WithOnlyUp(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.OnlyUp = enabled
+	}
+}
+
+// onlyUp 返回 stats 中 operstate 为 up 的接口, sysfs 中没有的接口视为不是 up
+func (n *netDev) onlyUp(stats map[string]TsNetDev) map[string]TsNetDev {
+	up := make(map[string]TsNetDev, len(stats))
+	for name, s := range stats {
+		if operUp(n.args.SysfsRoot, name) {
+			up[name] = s
+		}
+	}
+	return up
+}
+
+func operUp(root, name string) bool {
+	b, err := os.ReadFile(filepath.Join(root, "class", "net", name, "operstate"))
+	return err == nil && strings.TrimSpace(string(b)) == "up"
+}
@@ -0,0 +1,45 @@
+package mproc
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithOnlyUp(t *testing.T) {
+	root := t.TempDir()
+	operstate := func(name, state string) {
+		writeFile(t, filepath.Join(root, "class", "net", name, "operstate"), state+"\n")
+	}
+	operstate("eth0", "up")
+	operstate("eth1", "down")
+	operstate("lo", "unknown")
+	// eth2 在 sysfs 中不存在
+
+	n, err := newNetDev("test", time.Second, WithSysfsRoot(root), WithOnlyUp(true), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := snapshot("eth0", 0, 0, "eth1", 0, 0, "lo", 0, 0, "eth2", 0, 0)
+	cur := snapshot("eth0", 100, 10, "eth1", 200, 20, "lo", 300, 30, "eth2", 400, 40)
+	data := n.sample(prev, cur, sampleTiming{elapsed: time.Second})
+	if got := slices.Sorted(maps.Keys(data.PerInterface)); !slices.Equal(got, []string{"eth0"}) {
+		t.Fatalf("PerInterface = %v, want only eth0", got)
+	}
+	if data.BytesRx != 100 || data.BytesTx != 10 {
+		t.Fatalf("aggregate = %d/%d, want only eth0's 100/10", data.BytesRx, data.BytesTx)
+	}
+
+	// eth1 变为 up 后重新计入, 速率按最近一个间隔计算
+	operstate("eth1", "up")
+	next := snapshot("eth0", 200, 20, "eth1", 500, 50, "lo", 600, 60, "eth2", 800, 80)
+	data = n.sample(cur, next, sampleTiming{elapsed: time.Second})
+	if got := slices.Sorted(maps.Keys(data.PerInterface)); !slices.Equal(got, []string{"eth0", "eth1"}) {
+		t.Fatalf("PerInterface = %v, want eth0 and eth1", got)
+	}
+	if data.BytesRx != 100+300 || data.PerInterface["eth1"].BytesRx != 300 {
+		t.Fatalf("BytesRx = %d, eth1 = %+v; want 400 and 300", data.BytesRx, data.PerInterface["eth1"])
+	}
+}
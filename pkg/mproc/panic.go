@@ -0,0 +1,139 @@
+package mproc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// PanicPolicy 采样协程 panic 时的处理方式, 任何方式下监控都会停止, Err 返回 panic 的内容.
+// 回调中的 panic 只影响这一次回调: 按同样的方式报告后继续采样, PanicRethrow 时不恢复
+type PanicPolicy int
+
+const (
+	PanicRecover  PanicPolicy = iota // 恢复并记录日志 (默认)
+	PanicRethrow                     // 关闭输出后再次 panic, 使程序崩溃, 便于开发时发现错误
+	PanicCallback                    // 恢复并调用 WithPanicHandler 设置的函数, 没有设置时记录日志
+)
+
+// WithPanicPolicy 设置采样协程 panic 时的处理方式, 默认为 PanicRecover
+func WithPanicPolicy(policy PanicPolicy) netDevOpts {
+	return func(t *netDev) {
+		t.args.PanicPolicy = policy
+	}
+}
+
+// WithPanicHandler 采样协程 panic 时以 recover 的值调用 handler, 同时设置 PanicCallback
+func WithPanicHandler(handler func(recovered any)) netDevOpts {
+	return func(t *netDev) {
+		t.args.PanicPolicy = PanicCallback
+		t.args.PanicHandler = handler
+	}
+}
+
+// recoverPanic 在采样协程退出时按 PanicPolicy 处理 panic, 必须直接 defer
+func (t *netDev) recoverPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	t.fail(fmt.Errorf("netDev goroutine panic: %v", r))
+	switch t.args.PanicPolicy {
+	case PanicRethrow:
+		panic(r)
+	case PanicCallback:
+		if t.args.PanicHandler != nil {
+			t.args.PanicHandler(r)
+			return
+		}
+	}
+	if !t.args.QuietErrors {
+		mlog.Error(mlog.H{"error": "netDev goroutine panic", "reason": r})
+	}
+}
+
+// panicGuard 在回调 panic 时恢复并按 PanicPolicy 报告, 回调可能在其他协程中执行, 因此在创建时取出策略
+type panicGuard struct {
+	policy  PanicPolicy
+	handler func(recovered any)
+	quiet   bool
+	name    string // 日志中的监控名称
+}
+
+func (t *netDev) panicGuard() panicGuard {
+	return panicGuard{policy: t.args.PanicPolicy, handler: t.args.PanicHandler, quiet: t.args.QuietErrors, name: t.args.Name}
+}
+
+// run 执行用户的回调 fn, what 为日志中的回调名称; PanicRethrow 时不恢复
+func (g panicGuard) run(what string, fn func()) {
+	if g.policy != PanicRethrow {
+		defer g.recover(what)
+	}
+	fn()
+}
+
+// recover 必须直接 defer
+func (g panicGuard) recover(what string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if g.policy == PanicCallback && g.handler != nil {
+		g.handler(r)
+		return
+	}
+	if !g.quiet {
+		mlog.Error(mlog.H{"error": what + " panic", "reason": r, "name": g.name})
+	}
+}
+
+// guardCallback 返回在 callback panic 时恢复并按 PanicPolicy 报告的回调, 回调池中的回调同样适用.
+// 不读取 Reconfigure 替换的 args
+func (t *netDev) guardCallback(callback func(data TsCallData)) func(data TsCallData) {
+	g := t.panicGuard()
+	if callback == nil || g.policy == PanicRethrow {
+		return callback
+	}
+	return func(data TsCallData) {
+		defer g.recover("callback")
+		callback(data)
+	}
+}
+
+// guardHooks 为保存在 args 之外的回调加上 panicGuard; args 中的 OnStart、OnFinish、OnReset 在调用处处理,
+// 以免 Reconfigure 比较或替换时丢失
+func (t *netDev) guardHooks() {
+	g := t.panicGuard()
+	if g.policy == PanicRethrow {
+		return
+	}
+	if t.anomaly != nil && t.anomaly.onAnomaly != nil {
+		fn := t.anomaly.onAnomaly
+		t.anomaly.onAnomaly = func(data TsCallData, z float64) { g.run("anomaly callback", func() { fn(data, z) }) }
+	}
+	for _, th := range t.thresholds {
+		if fn := th.onExceed; fn != nil {
+			th.onExceed = func(iface string, rate TsInterfaceRate) { g.run("threshold callback", func() { fn(iface, rate) }) }
+		}
+		if fn := th.onRecover; fn != nil {
+			th.onRecover = func(iface string, rate TsInterfaceRate) { g.run("threshold callback", func() { fn(iface, rate) }) }
+		}
+	}
+	if t.spikes != nil && t.spikes.callback != nil {
+		fn := t.spikes.callback
+		t.spikes.callback = func(iface string, errsPerSec, dropPerSec int64) {
+			g.run("error spike callback", func() { fn(iface, errsPerSec, dropPerSec) })
+		}
+	}
+	if t.flaps != nil && t.flaps.callback != nil {
+		fn := t.flaps.callback
+		t.flaps.callback = func(iface string, ups, downs int, window time.Duration) {
+			g.run("link flap callback", func() { fn(iface, ups, downs, window) })
+		}
+	}
+	if t.batch != nil && t.batch.callback != nil {
+		fn := t.batch.callback
+		t.batch.callback = func(batch []TsCallData) { g.run("batch callback", func() { fn(batch) }) }
+	}
+}
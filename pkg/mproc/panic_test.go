@@ -0,0 +1,143 @@
+package mproc
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panicSource 每次读取都 panic
+type panicSource struct{}
+
+func (panicSource) Read() (map[string]TsNetDev, error) {
+	panic("broken reader")
+}
+
+func TestPanicPolicy(t *testing.T) {
+	t.Run("recover", func(t *testing.T) {
+		n, err := NewNetDev("panic", 10*time.Millisecond, WithStatsSource(panicSource{}), WithCallback(func(TsCallData) {}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Wait(); err == nil || !strings.Contains(err.Error(), "broken reader") {
+			t.Fatalf("Wait = %v, want panic error", err)
+		}
+		// 采样协程退出后 Reconfigure 不应阻塞
+		if err := n.Reconfigure(); err == nil {
+			t.Fatal("Reconfigure after panic = nil, want error")
+		}
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		got := make(chan any, 1)
+		n, err := NewNetDev("panic", 10*time.Millisecond, WithStatsSource(panicSource{}), WithCallback(func(TsCallData) {}),
+			WithPanicHandler(func(r any) { got <- r }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case r := <-got:
+			if r != "broken reader" {
+				t.Fatalf("handler got %v, want broken reader", r)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("panic handler was not called")
+		}
+		if err := n.Wait(); err == nil {
+			t.Fatal("Wait = nil, want panic error")
+		}
+	})
+
+	t.Run("rethrow", func(t *testing.T) {
+		if os.Getenv("MPROC_PANIC_RETHROW") == "1" {
+			n, err := NewNetDev("panic", 10*time.Millisecond, WithStatsSource(panicSource{}), WithCallback(func(TsCallData) {}),
+				WithPanicPolicy(PanicRethrow))
+			if err != nil {
+				t.Fatal(err)
+			}
+			n.Wait()
+			time.Sleep(time.Second) // 等待再次 panic 使进程退出
+			return
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestPanicPolicy$/^rethrow$")
+		cmd.Env = append(os.Environ(), "MPROC_PANIC_RETHROW=1")
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("process exited cleanly, want crash:\n%s", out)
+		}
+		if !strings.Contains(string(out), "panic: broken reader") {
+			t.Fatalf("output does not contain the rethrown panic:\n%s", out)
+		}
+	})
+}
+
+// 回调 panic 时只跳过这一次回调, 之后的采样继续回调
+func TestCallbackPanic(t *testing.T) {
+	for _, pooled := range []bool{false, true} {
+		calls := make(chan TsCallData, 10)
+		first := true
+		callback := func(data TsCallData) {
+			if first {
+				first = false
+				panic("bad callback")
+			}
+			calls <- data
+		}
+		source := NewMockSource(snapshot("eth0", 0, 0), snapshot("eth0", 100, 0), snapshot("eth0", 300, 0), snapshot("eth0", 600, 0))
+		handled := make(chan any, 1)
+		opts := []netDevOpts{WithStatsSource(source), WithCallback(callback), WithPanicHandler(func(r any) { handled <- r })}
+		if pooled {
+			pool := NewCallbackPool(1)
+			defer pool.Close()
+			opts = append(opts, WithCallbackPool(pool))
+		}
+		n, err := NewNetDev("panic", 10*time.Millisecond, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 2 {
+			select {
+			case <-calls:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("pooled=%v: sampling stopped after the callback panicked", pooled)
+			}
+		}
+		if r := <-handled; r != "bad callback" {
+			t.Fatalf("pooled=%v: handler got %v, want bad callback", pooled, r)
+		}
+		n.Close()
+		if err := n.Err(); err != nil {
+			t.Fatalf("pooled=%v: Err = %v, want nil", pooled, err)
+		}
+	}
+}
+
+// WithResetCallback 等其他回调 panic 时同样只跳过这一次回调
+func TestResetCallbackPanic(t *testing.T) {
+	calls := make(chan TsCallData, 10)
+	handled := make(chan any, 1)
+	source := NewMockSource(snapshot("eth0", 1000, 0), snapshot("eth0", 2000, 0), snapshot("eth0", 100, 0), snapshot("eth0", 600, 0))
+	n, err := NewNetDev("panic", 10*time.Millisecond, WithStatsSource(source),
+		WithCallback(func(data TsCallData) { calls <- data }),
+		WithResetCallback(func(string) { panic("bad reset callback") }),
+		WithPanicHandler(func(r any) { handled <- r }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		select {
+		case <-calls:
+		case <-time.After(5 * time.Second):
+			t.Fatal("sampling stopped after the reset callback panicked")
+		}
+	}
+	if r := <-handled; r != "bad reset callback" {
+		t.Fatalf("handler got %v, want bad reset callback", r)
+	}
+	n.Close()
+	if err := n.Err(); err != nil {
+		t.Fatalf("Err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,36 @@
+package mproc
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzReadNetDev 用任意内容驱动 Parse, 运行: go test -fuzz FuzzReadNetDev ./pkg/mproc
+func FuzzReadNetDev(f *testing.F) {
+	f.Add([]byte(fixtureNetDev))
+	f.Add([]byte(fixtureNetDev[:len(fixtureNetDev)-20]))
+	f.Add([]byte("eth0:123 4 0 0 0 0 0 0 456 7 0 0 0 0 0 0\n"))
+	f.Add([]byte("  eth0.100: -1 2 3\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stats, err := Parse(bytes.NewReader(data), nil)
+		if err != nil {
+			return
+		}
+		for iface, s := range stats {
+			if iface != s.Name {
+				t.Fatalf("interface %q has name %q", iface, s.Name)
+			}
+			for _, v := range []int64{
+				s.Receive.Bytes, s.Receive.Packets, s.Receive.Errs, s.Receive.Drop,
+				s.Receive.FIFO, s.Receive.Frame, s.Receive.Compressed, s.Receive.Multicast,
+				s.Transmit.Bytes, s.Transmit.Packets, s.Transmit.Errs, s.Transmit.Drop,
+				s.Transmit.FIFO, s.Transmit.Colls, s.Transmit.Carrier, s.Transmit.Compressed,
+			} {
+				if v < 0 {
+					t.Fatalf("interface %q has a negative counter: %+v", iface, s)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,267 @@
+package mproc
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	stats, err := Parse(strings.NewReader(fixtureNetDev), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d interfaces, want lo and eth0", len(stats))
+	}
+	eth0 := stats["eth0"]
+	if eth0.Name != "eth0" || eth0.Receive.Bytes != 1215645 || eth0.Receive.Packets != 2751 {
+		t.Fatalf("eth0 receive = %+v", eth0.Receive)
+	}
+	if eth0.Transmit.Bytes != 1782404 || eth0.Transmit.Packets != 4324 || eth0.Transmit.Colls != 427 {
+		t.Fatalf("eth0 transmit = %+v", eth0.Transmit)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	stats, err := Parse(strings.NewReader(fixtureNetDev), func(iface string) bool { return iface != "lo" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stats["lo"]; ok || len(stats) != 1 {
+		t.Fatalf("stats = %+v, want only eth0", stats)
+	}
+}
+
+// 字段不足的行被跳过, 负数或非法的计数按 0 处理
+func TestParseMalformed(t *testing.T) {
+	content := "eth0: 1 2 3\neth1: -5 x 0 0 0 0 0 0 7 0 0 0 0 0 0 0\n"
+	stats, err := Parse(strings.NewReader(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stats["eth0"]; ok {
+		t.Fatalf("short line parsed: %+v", stats["eth0"])
+	}
+	if eth1 := stats["eth1"]; eth1.Receive.Bytes != 0 || eth1.Receive.Packets != 0 || eth1.Transmit.Bytes != 7 {
+		t.Fatalf("eth1 = %+v, want invalid counters as 0", eth1)
+	}
+}
+
+// 字段不足的行以带行号和原始内容的 ParseError 报告, 其他接口照常解析
+func TestParseError(t *testing.T) {
+	content := fixtureNetDev + "eth9: 1 2 3\n"
+	lines := strings.Count(fixtureNetDev, "\n")
+	for _, headerMapping := range []bool{false, true} {
+		var skipped []*ParseError
+		stats, err := parse(strings.NewReader(content), nil, headerMapping, func(pe *ParseError) { skipped = append(skipped, pe) })
+		if err != nil || stats["eth0"].Receive.Bytes != 1215645 {
+			t.Fatalf("headerMapping=%v: eth0 = %+v, err = %v; want the other interfaces parsed", headerMapping, stats["eth0"], err)
+		}
+		if len(skipped) != 1 {
+			t.Fatalf("headerMapping=%v: skipped = %v, want one line", headerMapping, skipped)
+		}
+		if pe := skipped[0]; pe.Line != lines+1 || pe.Raw != "eth9: 1 2 3" || !errors.Is(pe, ErrTooFewFields) {
+			t.Fatalf("headerMapping=%v: ParseError = %+v, want line %d", headerMapping, pe, lines+1)
+		}
+	}
+
+	// 监控读取时跳过该行并带上文件路径, 持续存在的行只警告一次
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, content)
+	n := &netDev{args: &netDevArgs{Path: path}}
+	for range 2 {
+		stats, _, err := n.readNetDev()
+		if err != nil || len(stats) == 0 {
+			t.Fatalf("readNetDev = %v, %v; want the sample without the short line", stats, err)
+		}
+	}
+	want := fmt.Sprintf("%s:%d: too few fields: %q", path, lines+1, "eth9: 1 2 3")
+	if len(n.skipped) != 1 || !n.skipped[want] {
+		t.Fatalf("skipped = %v, want %q", n.skipped, want)
+	}
+	writeFile(t, path, fixtureNetDev)
+	if _, _, err := n.readNetDev(); err != nil || len(n.skipped) != 0 {
+		t.Fatalf("skipped = %v, err = %v after the line was fixed", n.skipped, err)
+	}
+}
+
+// 读取在行中间截断时忽略末尾没有换行的行, 不把部分记录当作计数
+func TestParseIncompleteLastLine(t *testing.T) {
+	content := netDevFile("eth0", 1000, 2000) + "  eth1: 123 0 0 0 0 0 0 0 45"
+	for _, headerMapping := range []bool{false, true} {
+		stats, err := parse(strings.NewReader(content), nil, headerMapping, nil)
+		if err != nil {
+			t.Fatalf("headerMapping=%v: %v", headerMapping, err)
+		}
+		if _, ok := stats["eth1"]; ok || stats["eth0"].Receive.Bytes != 1000 {
+			t.Fatalf("headerMapping=%v: stats = %+v, want only eth0", headerMapping, stats)
+		}
+	}
+}
+
+// 读到不完整的内容时重读一次, 第二次仍不完整时交给解析丢弃最后一行
+func TestRetryTruncated(t *testing.T) {
+	full := netDevFile("eth0", 1000, 2000, "eth1", 5, 6)
+	reads := []string{full[:len(full)-10], full}
+	calls := 0
+	read := retryTruncated(func() netDevRead {
+		calls++
+		return netDevRead{data: []byte(reads[min(calls, len(reads))-1])}
+	})
+	if r := read(); calls != 2 || string(r.data) != full {
+		t.Fatalf("after %d reads got %q, want the complete retry", calls, r.data)
+	}
+	if read(); calls != 3 {
+		t.Fatalf("complete read was retried: %d reads", calls)
+	}
+
+	calls = 0
+	reads = []string{full[:len(full)-10], full[:len(full)-3]}
+	if r := read(); calls != 2 || !r.truncated() {
+		t.Fatalf("after %d reads got %q, want a single retry", calls, r.data)
+	}
+}
+
+// 超过 bufio.Scanner 默认 64KB 限制的行也能解析
+func TestParseLongLine(t *testing.T) {
+	content := "eth0: 1000" + strings.Repeat(" ", 100<<10) + "0 0 0 0 0 0 0 2000 0 0 0 0 0 0 0\n"
+	stats, err := Parse(strings.NewReader(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eth0 := stats["eth0"]; eth0.Receive.Bytes != 1000 || eth0.Transmit.Bytes != 2000 {
+		t.Fatalf("eth0 = %+v, want 1000/2000", eth0)
+	}
+}
+
+// 表头中的列顺序与内核不同时按列名解析
+func TestParseHeaderMapping(t *testing.T) {
+	content := `Inter-|   Receive                                                |  Transmit
+ face |packets bytes    errs drop fifo frame compressed multicast|colls bytes    packets errs drop fifo carrier compressed
+  eth0: 2751 1215645 1 2 0 0 0 9 427 1782404 4324 3 4 0 0 0
+`
+	stats, err := parse(strings.NewReader(content), nil, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eth0 := stats["eth0"]
+	want := TsNetDev{
+		Name:     "eth0",
+		Receive:  TsNetDevInfo{Bytes: 1215645, Packets: 2751, Errs: 1, Drop: 2, Multicast: 9},
+		Transmit: TsNetDevInfo{Bytes: 1782404, Packets: 4324, Errs: 3, Drop: 4, Colls: 427},
+	}
+	if eth0 != want {
+		t.Fatalf("eth0 = %+v, want %+v", eth0, want)
+	}
+
+	// 默认按位置解析, 结果与列名不符
+	if stats, _ := Parse(strings.NewReader(content), nil); stats["eth0"].Receive.Bytes != 2751 {
+		t.Fatalf("positional Receive.Bytes = %d, want 2751", stats["eth0"].Receive.Bytes)
+	}
+	// 没有表头时退回按位置解析
+	if stats, _ := parse(strings.NewReader(fixtureNetDev[strings.Index(fixtureNetDev, "    lo:"):]), nil, true, nil); stats["eth0"].Receive.Bytes != 1215645 {
+		t.Fatalf("fallback Receive.Bytes = %d, want 1215645", stats["eth0"].Receive.Bytes)
+	}
+}
+
+// BenchmarkFilter 对比在 500 个接口的文件中按 500 个接口名过滤时, 逐个比较与集合查找的开销
+func BenchmarkFilter(b *testing.B) {
+	var ifaces []any
+	var names []string
+	for i := range 500 {
+		name := fmt.Sprintf("veth%04d", i)
+		ifaces = append(ifaces, name, i, i)
+		names = append(names, name)
+	}
+	content := netDevFile(ifaces...)
+	filters := map[string]Filter{
+		"slice": func(iface string) bool { return slices.Contains(names, iface) },
+		"map":   (&netDevArgs{Interfaces: names, interfaces: interfaceSet(names)}).filter(),
+	}
+	for _, name := range []string{"slice", "map"} {
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				if stats, _ := Parse(strings.NewReader(content), filters[name]); len(stats) != 500 {
+					b.Fatalf("got %d interfaces", len(stats))
+				}
+			}
+		})
+	}
+}
+
+// 有些内核在计数较大时冒号后没有空格, 第一个字节数紧跟在冒号后面; 字段之间也可能混用制表符
+func TestParseGluedColon(t *testing.T) {
+	content := "Inter-|   Receive |  Transmit\n face |bytes packets|bytes packets\n" +
+		"  eth0:4294967296 2751 0 0 0 0 0 0 1782404 4324 0 0 0 427 0 0\n" +
+		"eth1:\t10\t1 0 0  0 0 0 0\t20 2 0 0 0 0 0 0\n" +
+		"    lo: 5 1 0 0 0 0 0 0 5 1 0 0 0 0 0 0\n"
+	stats, err := Parse(strings.NewReader(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("stats = %+v, want eth0, eth1 and lo", stats)
+	}
+	if eth0 := stats["eth0"]; eth0.Receive.Bytes != 4294967296 || eth0.Receive.Packets != 2751 || eth0.Transmit.Colls != 427 {
+		t.Fatalf("eth0 = %+v", eth0)
+	}
+	if eth1 := stats["eth1"]; eth1.Receive.Bytes != 10 || eth1.Transmit.Bytes != 20 || eth1.Transmit.Packets != 2 {
+		t.Fatalf("eth1 = %+v", eth1)
+	}
+}
+
+// 接口名是第一个冒号之前去掉首尾空白的部分, VLAN 子接口和带空格的名称也能识别
+func TestParseInterfaceNames(t *testing.T) {
+	const counters = " 10 1 0 0 0 0 0 0 20 2 0 0 0 0 0 0\n"
+	content := "Inter-|   Receive                                                |  Transmit\n" +
+		" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n" +
+		"eth0.100:" + counters +
+		"      lo:" + counters +
+		"  my if :" + counters +
+		"    eth0:" + counters
+	for _, headerMapping := range []bool{false, true} {
+		stats, err := parse(strings.NewReader(content), nil, headerMapping, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := slices.Sorted(maps.Keys(stats))
+		if want := []string{"eth0", "eth0.100", "lo", "my if"}; !slices.Equal(names, want) {
+			t.Fatalf("headerMapping=%v: names = %q, want %q", headerMapping, names, want)
+		}
+		for name, s := range stats {
+			if s.Name != name || s.Receive.Bytes != 10 || s.Transmit.Packets != 2 {
+				t.Fatalf("headerMapping=%v: %q = %+v", headerMapping, name, s)
+			}
+		}
+	}
+}
+
+// Columns 报告表头中的列顺序, 标准格式与按位置解析的顺序一致
+func TestColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, fixtureNetDev)
+	n, err := NewNetDev("columns", time.Hour, WithPath(path), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"rx_bytes", "rx_packets", "rx_errs", "rx_drop", "rx_fifo", "rx_frame", "rx_compressed", "rx_multicast",
+		"tx_bytes", "tx_packets", "tx_errs", "tx_drop", "tx_fifo", "tx_colls", "tx_carrier", "tx_compressed",
+	}
+	if got := n.Columns(); !slices.Equal(got, want) {
+		t.Fatalf("Columns = %v, want %v", got, want)
+	}
+	if len(want) != netDevFields {
+		t.Fatalf("standard layout has %d columns, parser expects %d", len(want), netDevFields)
+	}
+	n.Close()
+	if got := n.Columns(); got != nil {
+		t.Fatalf("Columns after Close = %v, want nil", got)
+	}
+}
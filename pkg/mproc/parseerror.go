@@ -0,0 +1,29 @@
+package mproc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooFewFields 接口行的计数字段少于 16 个 (或启用 WithHeaderMapping 时少于表头的列数)
+var ErrTooFewFields = errors.New("too few fields")
+
+// ParseError 网络设备文件中无法解析的行: 读取出错时作为错误返回, 可以用 errors.As 取得;
+// 字段不足的接口行被跳过, 监控以警告日志报告
+type ParseError struct {
+	Path string // 文件路径, 由 Parse 直接解析时为空
+	Line int    // 行号, 从 1 开始, 包括表头
+	Raw  string // 原始行内容
+	Err  error  // 原因, 例如 ErrTooFewFields 或 bufio.ErrTooLong
+}
+
+func (e *ParseError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %v: %q", e.Line, e.Err, e.Raw)
+	}
+	return fmt.Sprintf("%s:%d: %v: %q", e.Path, e.Line, e.Err, e.Raw)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
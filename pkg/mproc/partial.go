@@ -0,0 +1,32 @@
+package mproc
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// scanCompleteLines 与 bufio.ScanLines 相同, 但丢弃末尾没有换行的行:
+// 读取在行中间被截断时这一行的字段和计数都不可信, 不能当作一条完整的记录解析
+func scanCompleteLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) > 0 && bytes.IndexByte(data, '\n') < 0 {
+		return len(data), nil, nil
+	}
+	return bufio.ScanLines(data, atEOF)
+}
+
+// truncated 判断读取的内容是否在行中间结束, /proc/net/dev 的每一行都以换行结尾
+func (r netDevRead) truncated() bool {
+	return r.err == nil && len(r.data) > 0 && r.data[len(r.data)-1] != '\n'
+}
+
+// retryTruncated 读取的内容在行中间结束时 (例如读取过程中接口表缩小) 重新读取一次;
+// 仍不完整时返回第二次的结果, 由解析时丢弃不完整的最后一行
+func retryTruncated(read func() netDevRead) func() netDevRead {
+	return func() netDevRead {
+		r := read()
+		if r.truncated() {
+			return read()
+		}
+		return r
+	}
+}
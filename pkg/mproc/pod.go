@@ -0,0 +1,44 @@
+package mproc
+
+import "strings"
+
+// PodResolver 根据 veth 接口名查找所属的 Pod, 例如读取 /sys/fs/cgroup 或查询 kubelet,
+// 找不到时返回 ok 为 false
+type PodResolver func(iface string) (pod, namespace string, ok bool)
+
+type podAttribution struct {
+	pod, namespace string
+	ok             bool
+}
+
+// podTagger 为 veth 接口标记 Pod, 每个接口只解析一次, 接口消失后重新解析
+type podTagger struct {
+	resolve PodResolver
+	cache   map[string]podAttribution
+}
+
+func newPodTagger(resolve PodResolver) *podTagger {
+	return &podTagger{resolve: resolve, cache: make(map[string]podAttribution)}
+}
+
+func (p *podTagger) apply(data *TsCallData) {
+	for name := range p.cache {
+		if _, ok := data.PerInterface[name]; !ok {
+			delete(p.cache, name)
+		}
+	}
+	for name, rate := range data.PerInterface {
+		if !strings.HasPrefix(name, "veth") {
+			continue
+		}
+		a, ok := p.cache[name]
+		if !ok {
+			a.pod, a.namespace, a.ok = p.resolve(name)
+			p.cache[name] = a
+		}
+		if a.ok {
+			rate.Pod, rate.Namespace = a.pod, a.namespace
+			data.PerInterface[name] = rate
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package mproc
+
+import "testing"
+
+func TestPodTagger(t *testing.T) {
+	calls := 0
+	p := newPodTagger(func(iface string) (string, string, bool) {
+		calls++
+		if iface == "veth1234" {
+			return "web-0", "default", true
+		}
+		return "", "", false
+	})
+	sample := func() TsCallData {
+		data := TsCallData{PerInterface: map[string]TsInterfaceRate{
+			"eth0":     {},
+			"veth1234": {},
+			"veth9999": {},
+		}}
+		p.apply(&data)
+		return data
+	}
+
+	sample()
+	data := sample()
+	if r := data.PerInterface["veth1234"]; r.Pod != "web-0" || r.Namespace != "default" {
+		t.Fatalf("veth1234 = %+v, want web-0/default", r)
+	}
+	if r := data.PerInterface["veth9999"]; r.Pod != "" {
+		t.Fatalf("veth9999 = %+v, want no pod", r)
+	}
+	if r := data.PerInterface["eth0"]; r.Pod != "" {
+		t.Fatalf("eth0 = %+v, want no pod", r)
+	}
+	if calls != 2 {
+		t.Fatalf("resolver called %d times, want 2 (once per veth)", calls)
+	}
+}
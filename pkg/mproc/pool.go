@@ -0,0 +1,134 @@
+package mproc
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// poolQueueSize 单个监控待执行回调的上限, 超出时丢弃新采样并计数, 见 DroppedCallbacks
+const poolQueueSize = 1024
+
+// CallbackPool 多个监控共享的回调协程池, 限制同时执行的回调数量
+//
+// 同一个监控的回调按采样顺序逐个执行, 不同监控的回调可以并发执行;
+// 各监控轮流执行一次回调, 回调慢于采样间隔的监控不会独占协程
+type CallbackPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	ready  []*poolQueue // 有待执行回调的队列
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewCallbackPool 创建包含 size 个协程的回调池, size 小于 1 时按 1 处理
+func NewCallbackPool(size int) *CallbackPool {
+	p := &CallbackPool{}
+	p.cond = sync.NewCond(&p.mu)
+	for range max(size, 1) {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+// Close 执行完已提交的回调后停止所有协程, 之后提交的回调会被丢弃
+func (p *CallbackPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *CallbackPool) worker() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for len(p.ready) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.ready) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		q := p.ready[0]
+		p.ready = p.ready[1:]
+		p.mu.Unlock()
+
+		if q.runOne() {
+			// 还有待执行的回调时排到末尾, 关闭后也要执行完已提交的回调
+			p.mu.Lock()
+			p.ready = append(p.ready, q)
+			p.mu.Unlock()
+		}
+	}
+}
+
+// schedule 将队列放入待执行列表, 返回 false 表示回调池已关闭
+func (p *CallbackPool) schedule(q *poolQueue) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.ready = append(p.ready, q)
+	p.cond.Signal()
+	return true
+}
+
+// poolQueue 单个监控的回调队列, 同一时间最多由一个协程执行, 以保证顺序
+type poolQueue struct {
+	pool     *CallbackPool
+	callback func(data TsCallData)
+
+	mu      sync.Mutex
+	pending []TsCallData
+	running bool         // 是否已在待执行列表中或正在执行
+	dropped atomic.Int64 // pending 已满时丢弃的采样数
+}
+
+func newPoolQueue(pool *CallbackPool, callback func(data TsCallData)) *poolQueue {
+	return &poolQueue{pool: pool, callback: callback}
+}
+
+// dispatch 提交一次回调, 不会阻塞采样协程; 待执行的回调达到 poolQueueSize 时丢弃
+func (q *poolQueue) dispatch(data TsCallData) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= poolQueueSize {
+		q.dropped.Add(1)
+		return
+	}
+	q.pending = append(q.pending, data)
+	if q.running {
+		return
+	}
+	if q.pool.schedule(q) {
+		q.running = true
+	} else {
+		q.pending = nil
+	}
+}
+
+// runOne 执行一个待处理的回调, 返回是否还有待执行的回调; 没有时队列退出待执行列表
+func (q *poolQueue) runOne() bool {
+	q.mu.Lock()
+	if len(q.pending) == 0 {
+		q.running = false
+		q.mu.Unlock()
+		return false
+	}
+	data := q.pending[0]
+	q.pending = q.pending[1:]
+	q.mu.Unlock()
+
+	q.callback(data)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		q.running = false
+		return false
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package mproc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallbackPool(t *testing.T) {
+	pool := NewCallbackPool(2)
+
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	got := map[string][]int64{}
+	var wg sync.WaitGroup
+
+	const monitors, samples = 4, 10
+	wg.Add(monitors * samples)
+	var queues []*poolQueue
+	for range monitors {
+		queues = append(queues, newPoolQueue(pool, func(data TsCallData) {
+			defer wg.Done()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+
+			mu.Lock()
+			got[data.Name] = append(got[data.Name], data.BytesRx)
+			mu.Unlock()
+		}))
+	}
+	for i := range samples {
+		for m, q := range queues {
+			q.dispatch(TsCallData{Name: string(rune('a' + m)), BytesRx: int64(i)})
+		}
+	}
+	wg.Wait()
+	pool.Close()
+
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("peak concurrency = %d, want at most 2", p)
+	}
+	for name, values := range got {
+		if len(values) != samples {
+			t.Fatalf("monitor %s got %d callbacks, want %d", name, len(values), samples)
+		}
+		for i, v := range values {
+			if v != int64(i) {
+				t.Fatalf("monitor %s callbacks = %v, want in order", name, values)
+			}
+		}
+	}
+	if len(got) != monitors {
+		t.Fatalf("got callbacks from %d monitors, want %d", len(got), monitors)
+	}
+}
+
+func TestWithCallbackPool(t *testing.T) {
+	pool := NewCallbackPool(1)
+	defer pool.Close()
+	n := newFIFONetDev(t, time.Second, WithCallbackPool(pool))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	if data := n.next(); data.BytesRx != 1000 {
+		t.Fatalf("BytesRx = %d, want 1000", data.BytesRx)
+	}
+}
+
+// 回调慢于采样的监控不会独占协程, 同一个池中的其他监控仍能轮到
+func TestCallbackPoolRoundRobin(t *testing.T) {
+	pool := NewCallbackPool(1)
+	defer pool.Close()
+
+	slow := newPoolQueue(pool, func(TsCallData) { time.Sleep(10 * time.Millisecond) })
+	fast := make(chan int64, 3)
+	quick := newPoolQueue(pool, func(data TsCallData) { fast <- data.BytesRx })
+	for range 50 {
+		slow.dispatch(TsCallData{})
+	}
+	start := time.Now()
+	for i := range 3 {
+		quick.dispatch(TsCallData{BytesRx: int64(i)})
+	}
+	for i := range 3 {
+		select {
+		case got := <-fast:
+			if got != int64(i) {
+				t.Fatalf("fast callback %d got %d, want in order", i, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("fast monitor got no callback")
+		}
+	}
+	if took := time.Since(start); took > 250*time.Millisecond {
+		t.Fatalf("fast monitor waited %v for the slow one's backlog", took)
+	}
+}
+
+// 待执行的回调达到上限时丢弃新采样并计数
+func TestCallbackPoolQueueFull(t *testing.T) {
+	pool := NewCallbackPool(1)
+	defer pool.Close()
+	release := make(chan struct{})
+	q := newPoolQueue(pool, func(TsCallData) { <-release })
+	for range poolQueueSize + 2 {
+		q.dispatch(TsCallData{})
+	}
+	if got := q.dropped.Load(); got < 1 {
+		t.Fatalf("dropped = %d, want samples dropped beyond %d", got, poolQueueSize)
+	}
+	if n := func() int { q.mu.Lock(); defer q.mu.Unlock(); return len(q.pending) }(); n > poolQueueSize {
+		t.Fatalf("pending = %d, want at most %d", n, poolQueueSize)
+	}
+	close(release)
+}
@@ -0,0 +1,144 @@
+package mproc
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// preadFile 保持文件打开, 每次从偏移 0 用 pread 重新读取, 省去每次采样的 open/close 和路径解析.
+// procfs 不支持 mmap (返回 ENODEV), 因此 WithMmap 也使用这种方式
+type preadFile struct {
+	path string
+	proc string // 不为空时为进程的 <procRoot>/<pid> 目录, 每次读取前检查是否存在, 进程退出后已打开的文件仍可读取
+	f    *os.File
+	buf  []byte
+}
+
+// open 打开文件, 已打开时不做任何事
+func (p *preadFile) open() error {
+	if p.f != nil {
+		return nil
+	}
+	f, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	p.f = f
+	return nil
+}
+
+// read 读取整个文件; 文件不支持 pread (例如命名管道)、读取出错 (例如 ESTALE) 或读到空内容时
+// 关闭文件并退回 os.ReadFile, 下一次读取重新打开
+func (p *preadFile) read() netDevRead {
+	// 检查 proc 目录而不是向进程发送信号: WithProcRoot 的 proc 可能属于其他 PID 命名空间
+	if p.proc != "" {
+		if _, err := os.Stat(p.proc); errors.Is(err, fs.ErrNotExist) {
+			p.close()
+			return netDevRead{at: time.Now(), err: &fs.PathError{Op: "read", Path: p.path, Err: fs.ErrNotExist}}
+		}
+	}
+	if err := p.open(); err != nil {
+		return netDevRead{at: time.Now(), err: err}
+	}
+	if p.buf == nil {
+		p.buf = make([]byte, 4096)
+	}
+	n := 0
+	for {
+		m, err := p.f.ReadAt(p.buf[n:], int64(n))
+		n += m
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			p.close()
+			return readFile(p.path)
+		}
+		if n == len(p.buf) {
+			p.buf = append(p.buf, make([]byte, len(p.buf))...)
+		}
+	}
+	if n == 0 {
+		p.close() // 文件被替换或描述符失效
+		return readFile(p.path)
+	}
+	// 返回副本, 解析结果不会引用下一次读取覆盖的缓冲区
+	return netDevRead{data: append([]byte(nil), p.buf[:n]...), at: time.Now()}
+}
+
+func (p *preadFile) close() {
+	if p.f != nil {
+		p.f.Close()
+		p.f = nil
+	}
+}
+
+// WithKeepOpen 创建监控时打开网络设备文件, 之后每次采样用 pread 从保持打开的描述符读取,
+// 避免重复的路径解析 (例如 /proc/<pid> 中的符号链接) 和 open/close 的开销.
+// 描述符失效 (读取出错或读到空内容) 时重新打开; 监控进程时仍能发现进程退出
+func WithKeepOpen(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.KeepOpen = enabled
+	}
+}
+
+// WithMmap 实验性: 与 WithKeepOpen 相同. procfs 不支持 mmap, 保持文件打开并用 pread 读取,
+// 减少 10ms 以下采样间隔时 open/close 的开销
+func WithMmap(enabled bool) netDevOpts {
+	return WithKeepOpen(enabled)
+}
+
+// reader 返回读取 path 的函数, 读到在行中间结束的内容时重读一次; 只在采样协程中或启动前调用
+func (n *netDev) reader(path string) func() netDevRead {
+	read := retryTruncated(n.fileReader(path))
+	if n.args.SharedRead {
+		return n.sharedReader(path, read)
+	}
+	return read
+}
+
+// fileReader 返回直接读取 path 的函数
+func (n *netDev) fileReader(path string) func() netDevRead {
+	if !n.args.KeepOpen {
+		return func() netDevRead { return readFile(path) }
+	}
+	if n.files == nil {
+		n.files = make(map[string]*preadFile)
+	}
+	f, ok := n.files[path]
+	if !ok {
+		f = &preadFile{path: path}
+		if n.args.PID > 0 && path == n.args.Path {
+			f.proc = filepath.Dir(filepath.Dir(path)) // <procRoot>/<pid>/net/dev
+		}
+		n.files[path] = f
+	}
+	return f.read
+}
+
+// openFiles 创建监控时打开 WithKeepOpen 的文件, 打开失败时在第一次读取时重试并报告错误
+func (n *netDev) openFiles() {
+	if !n.args.KeepOpen || n.source != nil {
+		return
+	}
+	paths := n.args.Paths
+	if len(paths) == 0 {
+		paths = []string{n.args.Path}
+	}
+	for _, path := range paths {
+		n.reader(path)
+		n.files[path].open()
+	}
+}
+
+// closeFiles 关闭 WithKeepOpen 保持打开的文件
+func (n *netDev) closeFiles() {
+	for _, f := range n.files {
+		f.close()
+	}
+	n.files = nil
+}
@@ -0,0 +1,195 @@
+package mproc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, fixtureNetDev)
+	f := &preadFile{path: path}
+	defer f.close()
+
+	r := f.read()
+	if r.err != nil || string(r.data) != fixtureNetDev {
+		t.Fatalf("read = %q, %v; want the fixture", r.data, r.err)
+	}
+	// 同一个文件描述符读取到变长后的内容, 超过初始缓冲区
+	long := netDevFile("eth0", 1, 2) + strings.Repeat(" dummy0: 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n", 200)
+	writeFile(t, path, long)
+	if r = f.read(); r.err != nil || string(r.data) != long {
+		t.Fatalf("second read has %d bytes, %v; want %d bytes", len(r.data), r.err, len(long))
+	}
+	stats, err := Parse(strings.NewReader(string(r.data)), nil)
+	if err != nil || stats["eth0"].Receive.Bytes != 1 {
+		t.Fatalf("parsed = %+v, %v", stats["eth0"], err)
+	}
+
+	os.Remove(path)
+	f.close()
+	if r = f.read(); !os.IsNotExist(r.err) {
+		t.Fatalf("read after remove = %v, want not exist", r.err)
+	}
+}
+
+func BenchmarkReadNetDev(b *testing.B) {
+	if _, err := os.Stat("/proc/net/dev"); err != nil {
+		b.Skip("no /proc/net/dev")
+	}
+	b.Run("open", func(b *testing.B) {
+		for range b.N {
+			if r := readFile("/proc/net/dev"); r.err != nil {
+				b.Fatal(r.err)
+			}
+		}
+	})
+	b.Run("pread", func(b *testing.B) {
+		f := &preadFile{path: "/proc/net/dev"}
+		defer f.close()
+		for range b.N {
+			if r := f.read(); r.err != nil {
+				b.Fatal(r.err)
+			}
+		}
+	})
+}
+
+// WithMmap 与 WithKeepOpen 相同, 保持文件打开并每次用 pread 读取最新内容
+func TestWithMmap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 100, 0))
+	n, err := newNetDev("test", time.Second, WithPath(path), WithMmap(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.closeFiles()
+
+	for _, rx := range []int{100, 600} {
+		writeFile(t, path, netDevFile("eth0", rx, 0))
+		stats, _, err := n.readNetDev()
+		if err != nil || stats["eth0"].Receive.Bytes != int64(rx) {
+			t.Fatalf("stats = %+v, %v; want rx %d", stats, err, rx)
+		}
+	}
+	if f := n.files[path]; f == nil || f.f == nil {
+		t.Fatal("file not kept open between reads")
+	}
+}
+
+func TestWithKeepOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 100, 0))
+	held, err := newNetDev("held", time.Second, WithPath(path), WithKeepOpen(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.closeFiles()
+	if f := held.files[path]; f == nil || f.f == nil {
+		t.Fatal("file not opened at construction")
+	}
+	perSample, err := newNetDev("open", time.Second, WithPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{
+		netDevFile("eth0", 100, 0),
+		netDevFile("eth0", 600, 50, "lo", 7, 7),
+		netDevFile("lo", 9, 9),
+	} {
+		writeFile(t, path, content)
+		got, _, err := held.readNetDev()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _, err := perSample.readNetDev()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("held descriptor read %+v, open per sample read %+v", got, want)
+		}
+	}
+	if f := held.files[path]; f == nil || f.f == nil {
+		t.Fatal("file not kept open between reads")
+	}
+
+	// 旧文件被清空并替换后, 保持打开的描述符读到空内容, 重新打开
+	replacement := path + ".new"
+	writeFile(t, replacement, netDevFile("eth0", 1, 0))
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatal(err)
+	}
+	stats, _, err := held.readNetDev()
+	if err != nil || stats["eth0"].Receive.Bytes != 1 {
+		t.Fatalf("after replace stats = %+v, %v; want rx 1", stats, err)
+	}
+}
+
+func TestWithKeepOpenProcessExit(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	n, err := NewNetDevForPID(cmd.Process.Pid, "exited", 20*time.Millisecond, WithKeepOpen(true), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		cmd.Process.Kill()
+		t.Fatal(err)
+	}
+	defer n.Close()
+	// 进程退出后保持打开的描述符仍可读取, 监控仍需停止
+	cmd.Process.Kill()
+	cmd.Wait()
+
+	select {
+	case <-n.Done():
+		if !errors.Is(n.Err(), ErrProcessExited) {
+			t.Fatalf("Err = %v, want ErrProcessExited", n.Err())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor did not stop after the process exited")
+	}
+}
+
+// WithProcRoot 的 proc 属于其他 PID 命名空间时, 按 <procRoot>/<pid> 是否存在判断进程退出
+func TestWithKeepOpenProcRoot(t *testing.T) {
+	root := t.TempDir()
+	const pid = 999999 // 当前命名空间中不存在的进程
+	writeFile(t, filepath.Join(root, "999999", "net", "dev"), netDevFile("eth0", 0, 0))
+	samples := make(chan TsCallData, 16)
+	n, err := NewNetDevForPID(pid, "other", 20*time.Millisecond, WithProcRoot(root), WithKeepOpen(true),
+		WithCallback(func(data TsCallData) { samples <- data }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	writeFile(t, filepath.Join(root, "999999", "net", "dev"), netDevFile("eth0", 1000, 0))
+	select {
+	case <-samples:
+	case <-n.Done():
+		t.Fatalf("monitor stopped: %v", n.Err())
+	case <-time.After(5 * time.Second):
+		t.Fatal("no sample")
+	}
+
+	os.RemoveAll(filepath.Join(root, "999999"))
+	select {
+	case <-n.Done():
+		if !errors.Is(n.Err(), ErrProcessExited) {
+			t.Fatalf("Err = %v, want ErrProcessExited", n.Err())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor did not stop after the proc directory disappeared")
+	}
+}
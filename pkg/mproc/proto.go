@@ -0,0 +1,272 @@
+package mproc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// protoMaxMessageSize 单个消息的最大长度, 防止损坏的长度前缀导致大量分配
+const protoMaxMessageSize = 16 << 20
+
+// protoWriter 将每个采样编码为 protobuf Sample 消息, 以 varint 长度前缀写入 w
+type protoWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write 写入一个带长度前缀的消息, w 为 unix socket 等连接时应注意写入可能阻塞采样协程
+func (p *protoWriter) Write(data TsCallData) error {
+	buf := frameSample(data)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.w.Write(buf)
+	return err
+}
+
+// Close 不关闭 w, 与 WithNDJSON 和 WithBinaryLog 一致由调用方管理
+func (p *protoWriter) Close() error {
+	return nil
+}
+
+// frameSample 返回带 varint 长度前缀的 Sample 消息
+func frameSample(data TsCallData) []byte {
+	msg := encodeSample(data)
+	buf := binary.AppendUvarint(make([]byte, 0, len(msg)+binary.MaxVarintLen64), uint64(len(msg)))
+	return append(buf, msg...)
+}
+
+// WithProtoWriter 将每个采样编码为 protobuf 并以 varint 长度前缀写入 w, 用 ProtoReader 读取;
+// 比 JSON 更紧凑, 适合高频采样时通过 unix socket 交给本地聚合程序. 监控关闭时不关闭 w
+func WithProtoWriter(w io.Writer) netDevOpts {
+	return func(t *netDev) {
+		WithSink(&protoWriter{w: w})(t)
+	}
+}
+
+// ProtoReader 读取 WithProtoWriter 写入的采样
+type ProtoReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewProtoReader 从 r 读取带长度前缀的 Sample 消息
+func NewProtoReader(r io.Reader) *ProtoReader {
+	return &ProtoReader{r: bufio.NewReader(r)}
+}
+
+// Read 读取下一个采样, 没有更多数据时返回 io.EOF
+func (p *ProtoReader) Read() (TsCallData, error) {
+	size, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		return TsCallData{}, err
+	}
+	if size > protoMaxMessageSize {
+		return TsCallData{}, fmt.Errorf("proto message too large: %d bytes", size)
+	}
+	if uint64(cap(p.buf)) < size {
+		p.buf = make([]byte, size)
+	}
+	p.buf = p.buf[:size]
+	if _, err := io.ReadFull(p.r, p.buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return TsCallData{}, err
+	}
+	return decodeSample(p.buf)
+}
+
+// encodeSample 将采样编码为 Sample 消息, 零值字段按 proto3 的约定省略
+//
+//	Sample {
+//	  string name = 1;
+//	  int64 interval_ns = 2;
+//	  int64 bytes_rx = 3;   int64 bytes_tx = 4;
+//	  int64 delta_rx = 5;   int64 delta_tx = 6;
+//	  int64 packets_rx = 7; int64 packets_tx = 8;
+//	  int64 errs_rx = 9;    int64 errs_tx = 10;
+//	  int64 drop_rx = 11;   int64 drop_tx = 12;
+//	  map<string, string> labels = 13;
+//	  repeated Interface interfaces = 14;
+//	  int64 prev_sampled_at_unix_nano = 15;
+//	  int64 sampled_at_unix_nano = 16;
+//	  map<string, double> derived = 17;
+//	  int64 read_duration_ns = 18;
+//	}
+//	Interface {
+//	  string name = 1;
+//	  int64 bytes_rx = 2; int64 bytes_tx = 3;
+//	  int64 delta_rx = 4; int64 delta_tx = 5;
+//	}
+func encodeSample(data TsCallData) []byte {
+	var b []byte
+	if data.Name != "" {
+		b = pbBytes(b, 1, []byte(data.Name))
+	}
+	for i, v := range []int64{
+		int64(data.Interval), data.BytesRx, data.BytesTx, data.DeltaRx, data.DeltaTx,
+		data.PacketsRx, data.PacketsTx, data.ErrsRx, data.ErrsTx, data.DropRx, data.DropTx,
+	} {
+		b = pbInt64(b, i+2, v)
+	}
+	for _, k := range slices.Sorted(maps.Keys(data.Labels)) {
+		b = pbBytes(b, 13, pbLabel(k, data.Labels[k]))
+	}
+	for _, name := range slices.Sorted(maps.Keys(data.PerInterface)) {
+		r := data.PerInterface[name]
+		m := pbBytes(nil, 1, []byte(name))
+		for i, v := range []int64{r.BytesRx, r.BytesTx, r.DeltaRx, r.DeltaTx} {
+			m = pbInt64(m, i+2, v)
+		}
+		b = pbBytes(b, 14, m)
+	}
+	for i, at := range []time.Time{data.PrevSampledAt, data.SampledAt} {
+		if !at.IsZero() {
+			b = pbInt64(b, 15+i, at.UnixNano())
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(data.Derived)) {
+		b = pbBytes(b, 17, pbDouble(pbBytes(nil, 1, []byte(name)), 2, data.Derived[name]))
+	}
+	b = pbInt64(b, 18, int64(data.ReadDuration))
+	return b
+}
+
+func pbInt64(b []byte, field int, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	return pbVarint(b, field, uint64(v))
+}
+
+// decodeSample 解码 Sample 消息, 忽略未知字段
+func decodeSample(msg []byte) (TsCallData, error) {
+	var data TsCallData
+	ints := []*int64{
+		nil, nil, nil, &data.BytesRx, &data.BytesTx, &data.DeltaRx, &data.DeltaTx,
+		&data.PacketsRx, &data.PacketsTx, &data.ErrsRx, &data.ErrsTx, &data.DropRx, &data.DropTx,
+	}
+	err := pbFields(msg, func(field int, v uint64, b []byte) error {
+		switch {
+		case field == 1:
+			data.Name = string(b)
+		case field == 2:
+			data.Interval = time.Duration(v)
+		case field < len(ints):
+			*ints[field] = int64(v)
+		case field == 13:
+			var k, val string
+			if err := pbFields(b, func(f int, _ uint64, s []byte) error {
+				if f == 1 {
+					k = string(s)
+				} else if f == 2 {
+					val = string(s)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if data.Labels == nil {
+				data.Labels = make(map[string]string)
+			}
+			data.Labels[k] = val
+		case field == 14:
+			var name string
+			var r TsInterfaceRate
+			rates := []*int64{nil, nil, &r.BytesRx, &r.BytesTx, &r.DeltaRx, &r.DeltaTx}
+			if err := pbFields(b, func(f int, v uint64, s []byte) error {
+				if f == 1 {
+					name = string(s)
+				} else if f < len(rates) && rates[f] != nil {
+					*rates[f] = int64(v)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			r.BytesRxF, r.BytesTxF = float64(r.BytesRx), float64(r.BytesTx)
+			if data.PerInterface == nil {
+				data.PerInterface = make(map[string]TsInterfaceRate)
+			}
+			data.PerInterface[name] = r
+		case field == 15:
+			data.PrevSampledAt = time.Unix(0, int64(v))
+		case field == 16:
+			data.SampledAt = time.Unix(0, int64(v))
+		case field == 17:
+			var name string
+			var value float64
+			if err := pbFields(b, func(f int, v uint64, s []byte) error {
+				if f == 1 {
+					name = string(s)
+				} else if f == 2 {
+					value = math.Float64frombits(v)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if data.Derived == nil {
+				data.Derived = make(map[string]float64)
+			}
+			data.Derived[name] = value
+		case field == 18:
+			data.ReadDuration = time.Duration(v)
+		}
+		return nil
+	})
+	// 浮点速率没有单独编码, 由截断后的速率还原
+	data.BytesRxF, data.BytesTxF = float64(data.BytesRx), float64(data.BytesTx)
+	return data, err
+}
+
+// pbFields 依次回调消息中的字段, varint 字段的值在 v 中, 长度分隔字段的内容在 b 中
+func pbFields(msg []byte, fn func(field int, v uint64, b []byte) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("proto: invalid field key")
+		}
+		msg = msg[n:]
+		field := int(key >> 3)
+		var v uint64
+		var b []byte
+		switch key & 7 {
+		case 0:
+			if v, n = binary.Uvarint(msg); n <= 0 {
+				return errors.New("proto: invalid varint")
+			}
+			msg = msg[n:]
+		case 1:
+			if len(msg) < 8 {
+				return io.ErrUnexpectedEOF
+			}
+			v, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case 2:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < size {
+				return errors.New("proto: invalid length")
+			}
+			b, msg = msg[n:n+int(size)], msg[n+int(size):]
+		case 5:
+			if len(msg) < 4 {
+				return io.ErrUnexpectedEOF
+			}
+			v, msg = uint64(binary.LittleEndian.Uint32(msg)), msg[4:]
+		default:
+			return fmt.Errorf("proto: unsupported wire type %d", key&7)
+		}
+		if err := fn(field, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package mproc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	samples := []TsCallData{
+		{Name: "all", Interval: time.Second, BytesRx: 1000, BytesTx: 2000, DeltaRx: 1000, DeltaTx: 2000,
+			PacketsRx: 10, ErrsTx: 1, DropRx: 3,
+			PrevSampledAt: time.Unix(1700000000, 0), SampledAt: time.Unix(1700000001, 500),
+			ReadDuration: 150 * time.Microsecond,
+			Derived:      map[string]float64{"rx_loss_ratio": 0.025},
+			Labels:       map[string]string{"hostname": "node1", "env": "prod"},
+			PerInterface: map[string]TsInterfaceRate{
+				"eth0": {BytesRx: 600, BytesTx: 2000, DeltaRx: 600, DeltaTx: 2000},
+				"eth1": {BytesRx: 400, DeltaRx: 400},
+			}},
+		{Name: "idle", Interval: 100 * time.Millisecond},
+		{Name: "neg", BytesRx: -1},
+	}
+
+	var buf bytes.Buffer
+	w := &protoWriter{w: &buf}
+	for _, s := range samples {
+		if err := w.Write(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewProtoReader(&buf)
+	for i, want := range samples {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("sample %d: %v", i, err)
+		}
+		for name, rate := range want.PerInterface {
+			rate.BytesRxF, rate.BytesTxF = float64(rate.BytesRx), float64(rate.BytesTx)
+			want.PerInterface[name] = rate
+		}
+		want.BytesRxF, want.BytesTxF = float64(want.BytesRx), float64(want.BytesTx)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sample %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("Read after last sample = %v, want io.EOF", err)
+	}
+}
+
+func TestProtoReaderTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	(&protoWriter{w: &buf}).Write(TsCallData{Name: "all", BytesRx: 1})
+	r := NewProtoReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if _, err := r.Read(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+// closeTracker 记录 Close 是否被调用
+type closeTracker struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+// 与 WithNDJSON 一致, 监控关闭时不关闭调用方的 w
+func TestProtoWriterKeepsWriterOpen(t *testing.T) {
+	var w closeTracker
+	n := newFIFONetDev(t, time.Second, WithProtoWriter(&w))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	n.next()
+	n.Close()
+	if w.closed {
+		t.Fatal("Close closed the caller's writer")
+	}
+	if w.Len() == 0 {
+		t.Fatal("no samples written")
+	}
+}
@@ -0,0 +1,130 @@
+package mproc
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// queuedSample 队列中的采样, at 为放入队列的时间
+type queuedSample struct {
+	at   time.Time
+	data TsCallData
+}
+
+// queueSink 将采样放入有界队列, 由单独的协程交给 run 发送, 用于 Graphite、remote-write、unix socket 等网络输出
+type queueSink struct {
+	name string // 错误中的输出名称
+
+	mu      sync.Mutex
+	closed  bool
+	queue   chan queuedSample
+	stopped chan struct{}
+	dropped int64 // 队列已满时丢弃的采样数
+}
+
+// newQueueSink 启动执行 run 的协程, run 在队列关闭并取完后返回
+func newQueueSink(name string, size int, run func(queue <-chan queuedSample)) *queueSink {
+	q := &queueSink{
+		name:    name,
+		queue:   make(chan queuedSample, size),
+		stopped: make(chan struct{}),
+	}
+	go func() {
+		defer close(q.stopped)
+		run(q.queue)
+	}()
+	return q
+}
+
+// Write 将采样放入队列, 不会阻塞采样协程; 队列已满时丢弃采样并返回错误
+func (q *queueSink) Write(data TsCallData) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	select {
+	case q.queue <- queuedSample{at: time.Now(), data: data}:
+		return nil
+	default:
+		q.dropped++
+		return fmt.Errorf("%s: queue full, %d samples dropped", q.name, q.dropped)
+	}
+}
+
+// Close 发送剩余的采样后停止
+func (q *queueSink) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.queue)
+	q.mu.Unlock()
+
+	<-q.stopped
+	return nil
+}
+
+// Dropped 返回队列已满时丢弃的采样数
+func (q *queueSink) Dropped() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// redialConn 复用一个连接发送, 失败时关闭连接, 间隔 redial 之后的下一次发送重新连接, 其间的数据被丢弃
+type redialConn struct {
+	dial    func() (net.Conn, error)
+	redial  time.Duration
+	timeout time.Duration // 单次发送的最长等待
+	target  mlog.H        // 错误日志中的目标, 例如 addr 或 path
+
+	conn     net.Conn
+	lastDial time.Time
+	failing  bool // 只记录连续失败中的第一个错误
+}
+
+func (c *redialConn) send(b []byte) {
+	if c.conn == nil {
+		if time.Since(c.lastDial) < c.redial {
+			return
+		}
+		c.lastDial = time.Now()
+		conn, err := c.dial()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		c.conn = conn
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(b); err != nil {
+		c.fail(err)
+		c.close()
+		return
+	}
+	c.failing = false
+}
+
+func (c *redialConn) fail(err error) {
+	if !c.failing {
+		h := mlog.H{"error": err.Error()}
+		maps.Copy(h, c.target)
+		mlog.Error(h)
+	}
+	c.failing = true
+}
+
+func (c *redialConn) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
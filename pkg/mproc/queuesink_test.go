@@ -0,0 +1,38 @@
+package mproc
+
+import (
+	"strings"
+	"testing"
+)
+
+// 队列已满时丢弃采样并返回错误, 由 WithSinkErrorHandler 或日志报告; 关闭时取完剩余的采样
+func TestQueueSinkFull(t *testing.T) {
+	release := make(chan struct{})
+	var sent []int64
+	q := newQueueSink("test", 1, func(queue <-chan queuedSample) {
+		<-release
+		for s := range queue {
+			sent = append(sent, s.data.BytesRx)
+		}
+	})
+
+	if err := q.Write(TsCallData{BytesRx: 1}); err != nil {
+		t.Fatal(err)
+	}
+	err := q.Write(TsCallData{BytesRx: 2})
+	if err == nil || !strings.Contains(err.Error(), "test: queue full, 1 samples dropped") {
+		t.Fatalf("Write = %v, want queue full error", err)
+	}
+	if q.Dropped() != 1 {
+		t.Fatalf("Dropped = %d, want 1", q.Dropped())
+	}
+
+	close(release)
+	q.Close()
+	if len(sent) != 1 || sent[0] != 1 {
+		t.Fatalf("sent = %v, want [1]", sent)
+	}
+	if err := q.Write(TsCallData{}); err != nil {
+		t.Fatalf("Write after Close = %v, want nil", err)
+	}
+}
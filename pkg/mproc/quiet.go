@@ -0,0 +1,16 @@
+package mproc
+
+// WithQuiet 不输出信息和警告日志, 包括没有设置 WithCallback 时默认回调输出的采样; 错误日志由 WithQuietErrors 控制.
+// 输出 (WithSink 等) 自身的错误日志不受影响
+func WithQuiet(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.Quiet = enabled
+	}
+}
+
+// WithQuietErrors 不输出错误日志, 读取失败等错误仍会计入 WithMaxReadErrors 并通过 Err 返回
+func WithQuietErrors(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.args.QuietErrors = enabled
+	}
+}
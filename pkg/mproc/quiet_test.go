@@ -0,0 +1,72 @@
+package mproc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// flakySource 每隔一次读取失败, 失败时返回 err
+type flakySource struct {
+	reads atomic.Int64
+	err   error
+}
+
+func (s *flakySource) Read() (map[string]TsNetDev, error) {
+	n := s.reads.Add(1)
+	if n%2 == 0 {
+		return nil, s.err
+	}
+	return snapshot("eth0", int(n)*1000, int(n)*100), nil
+}
+
+func TestWithQuiet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mproc.log")
+	mlog.SetNew(mlog.WithFile(path))
+
+	start := func(name string, opts ...netDevOpts) (*netDev, *flakySource) {
+		source := &flakySource{err: errors.New(name + " read failed")}
+		n, err := NewNetDev(name, 10*time.Millisecond, append(opts, WithStatsSource(source))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n, source
+	}
+	quiet, quietSource := start("quiet-monitor", WithQuiet(true), WithQuietErrors(true))
+	loud, loudSource := start("loud-monitor")
+	deadline := time.Now().Add(5 * time.Second)
+	for quietSource.reads.Load() < 8 || loudSource.reads.Load() < 8 {
+		if time.Now().After(deadline) {
+			t.Fatal("monitors did not sample")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	quiet.Close()
+	loud.Close()
+	<-quiet.Done()
+	<-loud.Done()
+	mlog.SetNew() // 写出剩余的日志并恢复默认输出
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+	// 对照: 没有 WithQuiet 的监控输出了采样和错误
+	if !strings.Contains(out, "loud-monitor") || !strings.Contains(out, "loud-monitor read failed") {
+		t.Fatalf("log does not contain the loud monitor:\n%s", out)
+	}
+	// 日志中的调用位置是实际输出日志的代码
+	if strings.Contains(out, "quiet.go") {
+		t.Fatalf("log entries report quiet.go as the caller:\n%s", out)
+	}
+	if strings.Contains(out, "quiet-monitor") {
+		t.Fatalf("quiet monitor logged:\n%s", out)
+	}
+}
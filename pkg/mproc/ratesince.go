@@ -0,0 +1,60 @@
+package mproc
+
+import (
+	"maps"
+	"time"
+)
+
+// pullState RateSince 上一次调用时的读取
+type pullState struct {
+	stats map[string]TsNetDev
+	at    time.Time
+}
+
+// RateSince 立即读取一次并返回与上一次调用 RateSince 之间的速率, 按实际经过的时间计算, 与采样间隔无关,
+// 适合按自己的节奏拉取的使用方式. 与定时采样互不影响: 不回调, 不写入输出和历史.
+// 第一次调用只记录基线, 速率为 0; 读取失败或监控已关闭时返回零值, 基线不变.
+// 在采样协程中执行, 不能在回调中调用
+func (t *netDev) RateSince() TsCallData {
+	result := make(chan TsCallData, 1)
+	pull := func() {
+		if t.replaying {
+			result <- TsCallData{}
+			return
+		}
+		stats, skew, err := t.readNetDev()
+		at := t.readTime()
+		if err != nil {
+			result <- TsCallData{}
+			return
+		}
+		if t.args.OnlyUp {
+			stats = t.onlyUp(stats)
+		}
+		prev := pullState{stats: stats, at: at}
+		if t.pulled != nil {
+			prev = *t.pulled
+		}
+		t.pulled = &pullState{stats: stats, at: at}
+
+		measured, _ := monotonicElapsed(prev.at, at)
+		elapsed := measured
+		if elapsed <= 0 {
+			elapsed = at.Sub(prev.at) // 时钟没有单调读数, 例如 WithClock
+		}
+		data := t.rates(prev.stats, stats, elapsed)
+		data.Name = t.args.Name
+		data.Interfaces = t.args.Interfaces
+		data.ReadSkew = skew
+		data.MonotonicElapsed = measured
+		data.PrevSampledAt, data.SampledAt = prev.at, at
+		data.Labels = maps.Clone(t.args.Labels)
+		result <- data
+	}
+	select {
+	case t.reconfig <- pull:
+		return <-result
+	case <-t.done:
+		return TsCallData{}
+	}
+}
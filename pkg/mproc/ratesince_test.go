@@ -0,0 +1,51 @@
+package mproc
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateSince(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	source := NewMockSource(snapshot("eth0", 0, 0), snapshot("eth0", 3000, 600), snapshot("eth0", 4000, 1600))
+	// 间隔很长, 定时采样不会读取
+	n, err := NewNetDev("pull", time.Hour, WithStatsSource(source), WithClock(clock), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	if data := n.RateSince(); data.BytesRx != 0 || data.Name != "pull" {
+		t.Fatalf("first RateSince = %+v, want the baseline without rates", data)
+	}
+	advance(3 * time.Second)
+	if data := n.RateSince(); data.BytesRx != 1000 || data.BytesTx != 200 || data.Interval != 3*time.Second {
+		t.Fatalf("RateSince after 3s = %d/%d over %v, want 1000/200 over 3s", data.BytesRx, data.BytesTx, data.Interval)
+	}
+	advance(500 * time.Millisecond)
+	data := n.RateSince()
+	if data.BytesRx != 2000 || data.BytesTx != 2000 || data.Interval != 500*time.Millisecond {
+		t.Fatalf("RateSince after 500ms = %d/%d over %v, want 2000/2000 over 500ms", data.BytesRx, data.BytesTx, data.Interval)
+	}
+	if want := clock(); !data.SampledAt.Equal(want) || !data.PrevSampledAt.Equal(want.Add(-500*time.Millisecond)) {
+		t.Fatalf("span = [%v, %v], want the last 500ms", data.PrevSampledAt, data.SampledAt)
+	}
+
+	n.Close()
+	<-n.Done()
+	if data := n.RateSince(); data.Name != "" {
+		t.Fatalf("RateSince after Close = %+v, want the zero value", data)
+	}
+}
@@ -0,0 +1,166 @@
+package mproc
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// ErrClosed 监控已经关闭
+var ErrClosed = errors.New("netDev is closed")
+
+// Reconfigure 在运行中应用选项, 不需要重建监控
+//
+// 可以修改的包括接口过滤 (WithInterfaces)、间隔 (WithInterval)、阈值 (WithAnomalySigma) 等只影响计算的选项;
+// 文件路径、回调、输出、历史等在创建时确定的选项会返回错误, 并且不应用本次的任何选项.
+// 间隔变化后的第一次采样按实际经过的时间计算速率, 新的配置从下一次采样开始生效.
+// 返回时采样协程已经完成替换; 在采样协程中执行, 不能在回调中调用
+func (t *netDev) Reconfigure(opts ...netDevOpts) error {
+	t.reconfigMu.Lock()
+	defer t.reconfigMu.Unlock()
+
+	// 只有采样协程会替换 args, 替换由 reconfigMu 保护的 Reconfigure 发起并等待完成, 此时读取是安全的
+	args := *t.args
+	args.Labels = maps.Clone(args.Labels)
+	args.Derived = maps.Clone(args.Derived)
+	args.Groups = maps.Clone(args.Groups)
+	next := &netDev{
+		args:       &args,
+		history:    t.history,
+		adaptive:   t.adaptive,
+		smoother:   t.smoother,
+		sinks:      slices.Clip(t.sinks),
+		thresholds: slices.Clip(t.thresholds),
+		pool:       t.pool,
+		inflight:   t.inflight,
+		pods:       t.pods,
+		types:      t.types,
+		details:    t.details,
+		source:     t.source,
+		renames:    t.renames,
+		metrics:    t.metrics,
+		classes:    t.classes,
+		dedup:      t.dedup,
+		tcp:        t.tcp,
+		now:        t.now,
+		baseline:   t.baseline,
+		spikes:     t.spikes,
+		flaps:      t.flaps,
+		batch:      t.batch,
+	}
+	if t.anomaly != nil {
+		anomaly := *t.anomaly
+		next.anomaly = &anomaly
+	}
+	for _, opt := range opts {
+		opt(next)
+	}
+
+	if err := t.checkReconfigure(next); err != nil {
+		for _, s := range next.sinks[len(t.sinks):] {
+			s.Close()
+		}
+		return err
+	}
+
+	applied := make(chan struct{})
+	apply := func() {
+		t.args = next.args
+		t.anomaly = next.anomaly
+		close(applied)
+	}
+	select {
+	case t.reconfig <- apply:
+		<-applied
+		return nil
+	case <-t.done:
+		return ErrClosed
+	}
+}
+
+// checkReconfigure 检查选项是否只修改了可以在运行中修改的配置
+func (t *netDev) checkReconfigure(next *netDev) error {
+	cur, args := t.args, next.args
+	var fixed []string
+	if args.Path != cur.Path || !slices.Equal(args.Paths, cur.Paths) || args.PID != cur.PID || next.source != t.source {
+		fixed = append(fixed, "path")
+	}
+	if args.ConcurrentRead != cur.ConcurrentRead {
+		fixed = append(fixed, "concurrent read")
+	}
+	if args.LockOSThread != cur.LockOSThread {
+		fixed = append(fixed, "lock os thread")
+	}
+	if reflect.ValueOf(args.Callback).Pointer() != reflect.ValueOf(cur.Callback).Pointer() || next.pool != t.pool || next.inflight != t.inflight {
+		fixed = append(fixed, "callback")
+	}
+	if len(next.sinks) != len(t.sinks) {
+		fixed = append(fixed, "sink")
+	}
+	if len(next.thresholds) != len(t.thresholds) {
+		fixed = append(fixed, "interface threshold")
+	}
+	if next.history != t.history {
+		fixed = append(fixed, "history")
+	}
+	if next.adaptive != t.adaptive {
+		fixed = append(fixed, "adaptive interval")
+	}
+	if next.smoother != t.smoother {
+		fixed = append(fixed, "smoothing")
+	}
+	if next.renames != t.renames || args.SysfsRoot != cur.SysfsRoot {
+		fixed = append(fixed, "rename tracking")
+	}
+	if next.types != t.types {
+		fixed = append(fixed, "interface types")
+	}
+	if next.details != t.details {
+		fixed = append(fixed, "interface details")
+	}
+	if next.pods != t.pods {
+		fixed = append(fixed, "pod resolver")
+	}
+	if next.tcp != t.tcp || args.SnmpPath != cur.SnmpPath {
+		fixed = append(fixed, "retrans ratio")
+	}
+	if next.baseline != t.baseline {
+		fixed = append(fixed, "rate baseline")
+	}
+	if next.spikes != t.spikes {
+		fixed = append(fixed, "error spike callback")
+	}
+	if next.flaps != t.flaps {
+		fixed = append(fixed, "link flap detection")
+	}
+	if next.batch != t.batch || args.BatchSize != cur.BatchSize {
+		fixed = append(fixed, "batch")
+	}
+	if args.MaxLifetime != cur.MaxLifetime || reflect.ValueOf(args.OnFinish).Pointer() != reflect.ValueOf(cur.OnFinish).Pointer() {
+		fixed = append(fixed, "max lifetime") // 到期时间在启动时确定
+	}
+	if args.Warmup != cur.Warmup {
+		fixed = append(fixed, "warmup") // 预热次数在启动时读取
+	}
+	if next.dedup != t.dedup {
+		fixed = append(fixed, "dedup")
+	}
+	if next.classes != t.classes {
+		fixed = append(fixed, "traffic classes")
+	}
+	if next.metrics != t.metrics {
+		fixed = append(fixed, "openmetrics")
+	}
+	if reflect.ValueOf(next.now).Pointer() != reflect.ValueOf(t.now).Pointer() {
+		fixed = append(fixed, "clock")
+	}
+	if len(fixed) > 0 {
+		return fmt.Errorf("options cannot be changed at runtime: %v", fixed)
+	}
+	if args.Interval < MinInterval {
+		return fmt.Errorf("invalid interval: %v, must be at least %v", args.Interval, MinInterval)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconfigureInterfaces(t *testing.T) {
+	n := newFIFONetDev(t, time.Second, WithInterfaces("eth0"))
+	n.feed(netDevFile("eth0", 0, 0, "eth1", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0, "eth1", 500, 0))
+	if data := n.next(); data.BytesRx != 1000 || len(data.PerInterface) != 1 {
+		t.Fatalf("before: BytesRx = %d, PerInterface = %v; want eth0 only", data.BytesRx, data.PerInterface)
+	}
+
+	if err := n.Reconfigure(WithInterfaces("eth1")); err != nil {
+		t.Fatal(err)
+	}
+	n.feed(netDevFile("eth0", 2000, 0, "eth1", 1000, 0)) // eth1 的基线
+	n.feed(netDevFile("eth0", 3000, 0, "eth1", 1500, 0))
+	n.next()
+	if data := n.next(); data.BytesRx != 500 || len(data.PerInterface) != 1 {
+		t.Fatalf("after: BytesRx = %d, PerInterface = %v; want eth1 only", data.BytesRx, data.PerInterface)
+	}
+}
+
+// 连续的 Reconfigure 在前一次替换完成后才读取配置, 使用 -race 运行
+func TestReconfigureBackToBack(t *testing.T) {
+	n := newFIFONetDev(t, time.Second)
+	for _, iface := range []string{"eth0", "eth1", "eth0", "eth1"} {
+		if err := n.Reconfigure(WithInterfaces(iface)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(n.args.Interfaces) != 1 || n.args.Interfaces[0] != "eth1" {
+		t.Fatalf("Interfaces = %v, want [eth1]", n.args.Interfaces)
+	}
+}
+
+func TestReconfigureRejectsFixedOptions(t *testing.T) {
+	n := newFIFONetDev(t, time.Second)
+	for name, opt := range map[string]netDevOpts{
+		"path":     WithPath("/tmp/other"),
+		"callback": WithCallback(func(TsCallData) {}),
+		"history":  WithHistory(time.Hour),
+		"interval": WithInterval(0),
+		"lifetime": WithMaxLifetime(time.Hour, nil),
+		"warmup":   WithWarmup(2),
+	} {
+		if err := n.Reconfigure(WithInterfaces("eth0"), opt); err == nil {
+			t.Fatalf("Reconfigure with %s succeeded", name)
+		}
+	}
+	if n.args.Interfaces != nil {
+		t.Fatalf("Interfaces = %v, want rejected options not applied", n.args.Interfaces)
+	}
+}
+
+func TestReconfigureClosed(t *testing.T) {
+	n, err := NewNetDev("test", time.Second, WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Close()
+	if err := n.Reconfigure(WithInterfaces("eth0")); err != ErrClosed {
+		t.Fatalf("Reconfigure after Close = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,224 @@
+package mproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+const (
+	remoteWriteQueueSize  = 1024 // 待发送采样的最大数量, 超出时丢弃新采样
+	remoteWriteMaxRetries = 3
+)
+
+// remoteWriter 将采样按批次以 Prometheus remote-write 协议推送
+type remoteWriter struct {
+	*queueSink
+	url      string
+	interval time.Duration
+	client   *http.Client
+}
+
+func newRemoteWriter(url string, interval time.Duration) *remoteWriter {
+	w := &remoteWriter{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	w.queueSink = newQueueSink("remote write", remoteWriteQueueSize, w.run)
+	return w
+}
+
+// run 每隔 interval 发送一个批次, 队列关闭时发送剩余的采样
+func (w *remoteWriter) run(queue <-chan queuedSample) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var batch []queuedSample
+	for {
+		select {
+		case s, ok := <-queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, s)
+		case <-ticker.C:
+			batch = w.flush(batch)
+		}
+	}
+}
+
+// flush 发送一个批次, 失败时按指数退避重试
+func (w *remoteWriter) flush(batch []queuedSample) []queuedSample {
+	if len(batch) == 0 {
+		return batch
+	}
+	body := snappyEncode(encodeWriteRequest(batch))
+
+	backoff := 100 * time.Millisecond
+	for attempt := 0; attempt < remoteWriteMaxRetries; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			break
+		}
+		mlog.Error(mlog.H{"error": err.Error(), "url": w.url, "attempt": attempt + 1})
+		if !retry {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return batch[:0]
+}
+
+// post 发送请求, 返回值表示失败时是否值得重试
+func (w *remoteWriter) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	// 5xx 和 429 可以重试, 其他 4xx 说明请求本身有问题
+	retry := resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("remote write: unexpected status %s", resp.Status)
+}
+
+// encodeWriteRequest 将采样编码为 prometheus.WriteRequest protobuf 消息
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(batch []queuedSample) []byte {
+	series := map[string][]byte{} // 按指标名和标签区分时间序列
+	var order []string
+	add := func(metric string, pairs [][2]string, ts int64, value float64) {
+		key := metric
+		for _, p := range pairs {
+			key += "\x00" + p[0] + "=" + p[1]
+		}
+		if _, ok := series[key]; !ok {
+			order = append(order, key)
+			// remote-write 要求包括 __name__ 在内的所有标签按名称排序, 大写开头的标签名排在 __name__ 之前
+			var labels []byte
+			for _, p := range withLabel(pairs, "__name__", metric) {
+				labels = pbBytes(labels, 1, pbLabel(p[0], p[1]))
+			}
+			series[key] = labels
+		}
+		var sample []byte
+		sample = pbDouble(sample, 1, value)
+		sample = pbVarint(sample, 2, uint64(ts))
+		series[key] = pbBytes(series[key], 2, sample)
+	}
+
+	for _, s := range batch {
+		ts := s.at.UnixMilli()
+		base := sampleLabels(s.data)
+		// 与 OpenMetrics 输出相同: 有 PerInterface 时按接口输出带 interface 标签的速率, 否则输出汇总
+		if s.data.PerInterface == nil {
+			add("mproc_netdev_receive_bytes_per_second", base, ts, s.data.BytesRxF)
+			add("mproc_netdev_transmit_bytes_per_second", base, ts, s.data.BytesTxF)
+		}
+		for _, iface := range slices.Sorted(maps.Keys(s.data.PerInterface)) {
+			r := s.data.PerInterface[iface]
+			labels := withLabel(base, "interface", iface)
+			add("mproc_netdev_receive_bytes_per_second", labels, ts, r.BytesRxF)
+			add("mproc_netdev_transmit_bytes_per_second", labels, ts, r.BytesTxF)
+		}
+		if s.data.ReadDuration > 0 {
+			add("mproc_netdev_read_duration_seconds", base, ts, s.data.ReadDuration.Seconds())
+		}
+		for _, name := range slices.Sorted(maps.Keys(s.data.Derived)) {
+			add("mproc_netdev_derived", withLabel(base, "derived", name), ts, s.data.Derived[name])
+		}
+	}
+
+	var req []byte
+	for _, key := range order {
+		req = pbBytes(req, 1, series[key])
+	}
+	return req
+}
+
+// sampleLabels 返回采样的标签, name 标签总是采样的 Name
+func sampleLabels(data TsCallData) [][2]string {
+	var pairs [][2]string
+	for name, value := range data.Labels {
+		if name != "name" {
+			pairs = append(pairs, [2]string{name, value})
+		}
+	}
+	return withLabel(pairs, "name", data.Name)
+}
+
+// withLabel 返回追加了一个标签的副本, remote-write 要求标签按名称排序
+func withLabel(pairs [][2]string, name, value string) [][2]string {
+	pairs = append(slices.Clone(pairs), [2]string{name, value})
+	slices.SortFunc(pairs, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+	return pairs
+}
+
+func pbLabel(name, value string) []byte {
+	var b []byte
+	b = pbBytes(b, 1, []byte(name))
+	b = pbBytes(b, 2, []byte(value))
+	return b
+}
+
+func pbBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func pbVarint(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+func pbDouble(b []byte, field int, v float64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|1)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+// snappyEncode 以 snappy 块格式编码, 只使用字面量块, 不做压缩
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		chunk := src[:min(len(src), 1<<16)]
+		src = src[len(chunk):]
+
+		n := len(chunk) - 1
+		switch {
+		case n < 60:
+			dst = append(dst, byte(n)<<2)
+		case n < 1<<8:
+			dst = append(dst, 60<<2, byte(n))
+		default:
+			dst = append(dst, 61<<2, byte(n), byte(n>>8))
+		}
+		dst = append(dst, chunk...)
+	}
+	return dst
+}
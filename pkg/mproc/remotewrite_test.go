@@ -0,0 +1,221 @@
+package mproc
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rwSeries 解码后的一条时间序列
+type rwSeries struct {
+	labels  map[string]string
+	names   []string // 标签名按编码的顺序, 包括 __name__
+	values  []float64
+	millis  []int64
+	metric  string
+	hasName bool
+}
+
+func TestRemoteWrite(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies [][]byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("Content-Encoding = %q", r.Header.Get("Content-Encoding"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n := newFIFONetDev(t, time.Second, WithRemoteWrite(srv.URL, time.Hour))
+	n.feed(netDevFile("eth0", 0, 0, "eth1", 0, 0))
+	before := time.Now()
+	n.feed(netDevFile("eth0", 3000, 1000, "eth1", 1000, 0))
+	n.next()
+	after := time.Now()
+	n.Close() // 关闭时发送剩余的批次
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 1 {
+		t.Fatalf("got %d requests, want 1", len(bodies))
+	}
+	series := decodeWriteRequest(t, snappyDecode(t, bodies[0]))
+	byKey := map[string]rwSeries{}
+	for _, s := range series {
+		byKey[s.metric+"/"+s.labels["interface"]] = s
+	}
+	for key, want := range map[string]float64{
+		"mproc_netdev_receive_bytes_per_second/eth0":  3000,
+		"mproc_netdev_transmit_bytes_per_second/eth0": 1000,
+		"mproc_netdev_receive_bytes_per_second/eth1":  1000,
+		"mproc_netdev_transmit_bytes_per_second/eth1": 0,
+	} {
+		s, ok := byKey[key]
+		if !ok {
+			t.Fatalf("missing series %s in %v", key, byKey)
+		}
+		// 关闭时可能还有一次读到空内容的采样
+		if len(s.values) == 0 || s.values[0] != want {
+			t.Fatalf("%s values = %v, want [%v]", key, s.values, want)
+		}
+		if s.millis[0] < before.UnixMilli() || s.millis[0] > after.UnixMilli() {
+			t.Fatalf("%s timestamp = %d, want the sample time", key, s.millis[0])
+		}
+		if s.labels["name"] != "test" {
+			t.Fatalf("%s labels = %v", key, s.labels)
+		}
+	}
+}
+
+// 没有 PerInterface 时输出不带 interface 标签的汇总速率
+func TestEncodeWriteRequestAggregate(t *testing.T) {
+	data := TsCallData{Name: "test", BytesRxF: 1.5, BytesTxF: 0.5}
+	series := decodeWriteRequest(t, encodeWriteRequest([]queuedSample{{at: time.Now(), data: data}}))
+	if len(series) != 2 {
+		t.Fatalf("got %d series, want 2", len(series))
+	}
+	for _, s := range series {
+		if _, ok := s.labels["interface"]; ok {
+			t.Fatalf("%s labels = %v, want no interface label", s.metric, s.labels)
+		}
+	}
+	if series[0].values[0] != 1.5 || series[1].values[0] != 0.5 {
+		t.Fatalf("values = %v/%v, want 1.5/0.5", series[0].values, series[1].values)
+	}
+}
+
+func TestWithLabelSorted(t *testing.T) {
+	got := withLabel([][2]string{{"host", "h"}, {"name", "n"}}, "iface", "eth0")
+	if got[0][0] != "host" || got[1][0] != "iface" || got[2][0] != "name" {
+		t.Fatalf("withLabel = %v, want sorted by name", got)
+	}
+}
+
+// 编码的标签包括 __name__ 在内按名称排序
+func TestEncodeWriteRequestLabelOrder(t *testing.T) {
+	s := queuedSample{at: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), data: TsCallData{Name: "test"}}
+	series := decodeWriteRequest(t, encodeWriteRequest([]queuedSample{s}))
+	if len(series) != 2 {
+		t.Fatalf("got %d series, want 2", len(series))
+	}
+	for _, s := range series {
+		if !slices.IsSorted(s.names) {
+			t.Fatalf("%s labels = %v, want sorted by name", s.metric, s.names)
+		}
+	}
+}
+
+func TestEncodeWriteRequestLabels(t *testing.T) {
+	data := TsCallData{Name: "test", Labels: map[string]string{"hostname": "h1", "name": "ignored"}}
+	series := decodeWriteRequest(t, encodeWriteRequest([]queuedSample{{at: time.Now(), data: data}}))
+	for _, s := range series {
+		if s.labels["hostname"] != "h1" || s.labels["name"] != "test" || len(s.labels) != 2 {
+			t.Fatalf("%s labels = %v, want hostname=h1 and name=test", s.metric, s.labels)
+		}
+	}
+}
+
+// snappyDecode 解码只包含字面量块的 snappy 数据
+func snappyDecode(t *testing.T, src []byte) []byte {
+	t.Helper()
+	size, n := binary.Uvarint(src)
+	src = src[n:]
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		if tag&3 != 0 {
+			t.Fatalf("unexpected snappy copy tag %#x", tag)
+		}
+		length, hdr := int(tag>>2)+1, 1
+		switch tag >> 2 {
+		case 60:
+			length, hdr = int(src[1])+1, 2
+		case 61:
+			length, hdr = int(src[1])|int(src[2])<<8+1, 3
+		}
+		dst = append(dst, src[hdr:hdr+length]...)
+		src = src[hdr+length:]
+	}
+	if len(dst) != int(size) {
+		t.Fatalf("snappy length = %d, want %d", len(dst), size)
+	}
+	return dst
+}
+
+// rwFields 遍历一条 protobuf 消息, fixed64 字段 (Sample.value) 同样传给 fn
+func rwFields(t *testing.T, b []byte, fn func(field int, varint uint64, fixed uint64, bytes []byte)) {
+	t.Helper()
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(b)
+			b = b[n:]
+			fn(field, v, 0, nil)
+		case 1:
+			fn(field, 0, binary.LittleEndian.Uint64(b), nil)
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			b = b[n:]
+			fn(field, 0, 0, b[:l])
+			b = b[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+}
+
+func decodeWriteRequest(t *testing.T, b []byte) []rwSeries {
+	var series []rwSeries
+	rwFields(t, b, func(_ int, _, _ uint64, ts []byte) {
+		s := rwSeries{labels: map[string]string{}}
+		rwFields(t, ts, func(field int, _, _ uint64, msg []byte) {
+			switch field {
+			case 1:
+				var name, value string
+				rwFields(t, msg, func(f int, _, _ uint64, v []byte) {
+					if f == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				s.names = append(s.names, name)
+				if name == "__name__" {
+					s.metric, s.hasName = value, true
+				} else {
+					s.labels[name] = value
+				}
+			case 2:
+				rwFields(t, msg, func(f int, varint, fixed uint64, _ []byte) {
+					if f == 1 {
+						s.values = append(s.values, math.Float64frombits(fixed))
+					} else {
+						s.millis = append(s.millis, int64(varint))
+					}
+				})
+			}
+		})
+		if !s.hasName || strings.TrimSpace(s.metric) == "" {
+			t.Fatalf("series without __name__: %v", s.labels)
+		}
+		series = append(series, s)
+	})
+	return series
+}
@@ -0,0 +1,65 @@
+package mproc
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// renameTracker 通过 sysfs 中的 ifindex 识别改名的接口, 使计数器跟随接口而不是名称
+type renameTracker struct {
+	root  string         // sysfs 挂载点
+	index map[string]int // 上一次采样中各接口的 ifindex, 改名后旧名称在 sysfs 中已不存在
+}
+
+func newRenameTracker(root string) *renameTracker {
+	return &renameTracker{root: root, index: make(map[string]int)}
+}
+
+// follow 返回 prev 的副本, 其中改名的接口换成新名称, 并记录 cur 中各接口的 ifindex
+func (r *renameTracker) follow(prev, cur map[string]TsNetDev) map[string]TsNetDev {
+	index := make(map[string]int, len(cur))
+	byIndex := make(map[int]string)
+	for name := range cur {
+		idx, ok := r.index[name]
+		if !ok {
+			if idx, ok = r.ifindex(name); !ok {
+				continue
+			}
+		}
+		index[name] = idx
+		byIndex[idx] = name
+	}
+
+	out, cloned := prev, false
+	for old, idx := range r.index {
+		if _, ok := cur[old]; ok {
+			continue
+		}
+		name, ok := byIndex[idx]
+		if _, existed := prev[name]; !ok || existed {
+			continue
+		}
+		if stats, ok := prev[old]; ok {
+			if !cloned {
+				out, cloned = maps.Clone(prev), true
+			}
+			delete(out, old)
+			stats.Name = name
+			out[name] = stats
+		}
+	}
+	r.index = index
+	return out
+}
+
+func (r *renameTracker) ifindex(name string) (int, bool) {
+	b, err := os.ReadFile(filepath.Join(r.root, "class", "net", name, "ifindex"))
+	if err != nil {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	return idx, err == nil
+}
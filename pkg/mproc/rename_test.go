@@ -0,0 +1,32 @@
+package mproc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenameTracking(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "class", "net", "eth0", "ifindex"), "2\n")
+	writeFile(t, filepath.Join(root, "class", "net", "lo", "ifindex"), "1\n")
+
+	n := newFIFONetDev(t, time.Second, WithSysfsRoot(root), WithRenameTracking(true))
+	n.feed(netDevFile("eth0", 5000, 0, "lo", 0, 0))
+	n.feed(netDevFile("eth0", 6000, 0, "lo", 0, 0))
+	n.next()
+
+	// eth0 改名为 enp3s0, ifindex 不变
+	if err := os.Rename(filepath.Join(root, "class", "net", "eth0"), filepath.Join(root, "class", "net", "enp3s0")); err != nil {
+		t.Fatal(err)
+	}
+	n.feed(netDevFile("enp3s0", 8000, 0, "lo", 0, 0))
+	data := n.next()
+	if data.BytesRx != 2000 {
+		t.Fatalf("BytesRx = %d, want 2000 carried across the rename", data.BytesRx)
+	}
+	if r, ok := data.PerInterface["enp3s0"]; !ok || r.BytesRx != 2000 {
+		t.Fatalf("PerInterface = %v, want enp3s0 at 2000", data.PerInterface)
+	}
+}
@@ -0,0 +1,98 @@
+package mproc
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// ReplayFrame 回放的一帧: 采集时间和当时的 /proc/net/dev 内容, Stats 为空时解析 Raw
+type ReplayFrame struct {
+	Time  time.Time
+	Stats map[string]TsNetDev
+	Raw   []byte
+}
+
+// NewReplay 按顺序回放采集的数据, 经过与实时采样相同的计算、回调和输出, 用于事后分析
+//
+// 速率按相邻帧的时间差计算; 默认不等待, 尽快回放, 见 WithReplaySpeed. 回放结束后监控自动停止.
+// Reconfigure 在帧之间生效, Interfaces 返回最近一帧中的接口; 没有可读取的文件, Flush 返回 false,
+// RateSince 和 Columns 返回零值
+func NewReplay(frames []ReplayFrame, opts ...netDevOpts) (*netDev, error) {
+	t, err := newNetDev("replay", time.Second, opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.replaying = true
+	t.run(func() { t.replay(frames) })
+	return t, nil
+}
+
+// WithReplaySpeed 按 speed 倍速回放, 1 为保持原始的帧间隔, 0 为不等待
+func WithReplaySpeed(speed float64) netDevOpts {
+	return func(t *netDev) {
+		t.args.ReplaySpeed = speed
+	}
+}
+
+func (n *netDev) replay(frames []ReplayFrame) {
+	defer n.stop()
+
+	var last *ReplayFrame
+	var lastStats map[string]TsNetDev
+	for i := range frames {
+		frame := &frames[i]
+		if i > 0 {
+			// 先等待再解析, 等待期间的 Reconfigure 对这一帧生效
+			var wait time.Duration
+			if n.args.ReplaySpeed > 0 {
+				wait = time.Duration(float64(frame.Time.Sub(frames[i-1].Time)) / n.args.ReplaySpeed)
+			}
+			if !n.replayWait(wait) {
+				return
+			}
+		}
+		stats := frame.Stats
+		if stats == nil {
+			var err error
+			if stats, err = n.parseNetDev(bytes.NewReader(frame.Raw), nil); err != nil {
+				if !n.args.QuietErrors {
+					mlog.Error(mlog.H{"error": err.Error(), "frame": i})
+				}
+				continue
+			}
+		}
+
+		if last != nil {
+			n.sample(lastStats, stats, sampleTiming{elapsed: frame.Time.Sub(last.Time), at: frame.Time})
+		}
+		n.seen.record(stats)
+		last, lastStats = frame, stats
+
+		select {
+		case <-n.done:
+			return
+		default:
+		}
+	}
+}
+
+// replayWait 在帧之间等待 d, 其间执行 Reconfigure 等交给采样协程的操作, 不会让调用方等到回放结束;
+// 返回 false 表示监控已关闭
+func (n *netDev) replayWait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-n.done:
+			return false
+		case apply := <-n.reconfig:
+			apply()
+		case reply := <-n.flushes:
+			reply <- flushResult{}
+		case <-timer.C:
+			return true
+		}
+	}
+}
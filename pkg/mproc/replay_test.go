@@ -0,0 +1,87 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	frames := []ReplayFrame{
+		{Time: t0, Raw: []byte(netDevFile("eth0", 0, 0))},
+		{Time: t0.Add(2 * time.Second), Raw: []byte(netDevFile("eth0", 4000, 1000))},
+		{Time: t0.Add(3 * time.Second), Stats: snapshot("eth0", 10000, 1000)},
+	}
+	var got []TsCallData
+	n, err := NewReplay(frames, WithCallback(func(data TsCallData) { got = append(got, data) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-n.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay did not finish")
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d samples, want 2", len(got))
+	}
+	if got[0].BytesRx != 2000 || got[0].BytesTx != 500 || got[0].Interval != 2*time.Second {
+		t.Fatalf("first sample = %+v, want 2000/500 over 2s", got[0])
+	}
+	if got[1].BytesRx != 6000 || got[1].BytesTx != 0 || got[1].Interval != time.Second {
+		t.Fatalf("second sample = %+v, want 6000/0 over 1s", got[1])
+	}
+	n.Close()
+}
+
+func TestReplaySpeed(t *testing.T) {
+	t0 := time.Now()
+	frames := []ReplayFrame{
+		{Time: t0, Stats: snapshot("eth0", 0, 0)},
+		{Time: t0.Add(time.Second), Stats: snapshot("eth0", 1000, 0)},
+	}
+	start := time.Now()
+	n, err := NewReplay(frames, WithReplaySpeed(10), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-n.stopped
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Fatalf("replay took %v, want at least 100ms at 10x", d)
+	}
+}
+
+// 按原始间隔回放时, 交给采样协程的操作在帧之间执行, 不等到回放结束
+func TestReplayServesRequests(t *testing.T) {
+	t0 := time.Now()
+	frames := []ReplayFrame{
+		{Time: t0, Raw: []byte(netDevFile("eth0", 0, 0, "eth1", 0, 0))},
+		{Time: t0.Add(time.Second), Raw: []byte(netDevFile("eth0", 1000, 0, "eth1", 500, 0))},
+		{Time: t0.Add(2 * time.Second), Raw: []byte(netDevFile("eth0", 2000, 0, "eth1", 1000, 0))},
+	}
+	samples := make(chan TsCallData, 4)
+	n, err := NewReplay(frames, WithReplaySpeed(1), WithCallback(func(data TsCallData) { samples <- data }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	start := time.Now()
+	if err := n.Reconfigure(WithInterfaces("eth1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n.Flush(); ok {
+		t.Fatal("Flush succeeded on a replay")
+	}
+	if cols := n.Columns(); cols != nil {
+		t.Fatalf("Columns = %v, want nil on a replay", cols)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("requests took %v, want them served between frames", d)
+	}
+
+	if data := <-samples; data.BytesRx != 500 || len(data.PerInterface) != 1 {
+		t.Fatalf("first sample = %d %v, want eth1 only after Reconfigure", data.BytesRx, data.PerInterface)
+	}
+}
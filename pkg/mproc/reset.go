@@ -0,0 +1,29 @@
+package mproc
+
+import (
+	"maps"
+	"slices"
+)
+
+// WithResetCallback 接口的计数器被重置 (接口重建或计数器被清零, 收发字节数或包数变小且不是增量可信的 32 位回绕) 时
+// 以接口名调用 callback. 重置的接口在这个间隔的速率按 0 计算, 不会出现尖峰, 之后以重置后的值为基线
+func WithResetCallback(callback func(iface string)) netDevOpts {
+	return func(t *netDev) {
+		t.args.OnReset = callback
+	}
+}
+
+// checkResets 按接口名顺序回调计数器被重置的接口
+func (n *netDev) checkResets(prev, cur map[string]TsNetDev) {
+	for _, name := range slices.Sorted(maps.Keys(cur)) {
+		p, ok := prev[name]
+		if !ok {
+			continue
+		}
+		c := cur[name]
+		if counterReset(p.Receive.Bytes, c.Receive.Bytes) || counterReset(p.Transmit.Bytes, c.Transmit.Bytes) ||
+			counterReset(p.Receive.Packets, c.Receive.Packets) || counterReset(p.Transmit.Packets, c.Transmit.Packets) {
+			n.panicGuard().run("reset callback", func() { n.args.OnReset(name) })
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package mproc
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithResetCallback(t *testing.T) {
+	var resets []string
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	WithResetCallback(func(iface string) { resets = append(resets, iface) })(n)
+
+	steps := []map[string]TsNetDev{
+		snapshot("eth0", 50_000_000_000, 1000, "eth1", 5000, 5000),
+		snapshot("eth0", 50_000_001_000, 2000, "eth1", 6000, 6000),
+		snapshot("eth0", 300, 100, "eth1", 7000, 7000),  // eth0 重建, 计数器从小值开始
+		snapshot("eth0", 1300, 600, "eth1", 8000, 8000), // 以重置后的值为基线
+		snapshot("eth0", 2300, 1100, "eth1", 9000, 9000),
+	}
+	var rx []int64
+	for i := 1; i < len(steps); i++ {
+		data := n.sample(steps[i-1], steps[i], sampleTiming{elapsed: time.Second})
+		rx = append(rx, data.PerInterface["eth0"].BytesRx)
+	}
+	if !slices.Equal(resets, []string{"eth0"}) {
+		t.Fatalf("resets = %q, want eth0 once", resets)
+	}
+	if want := []int64{1000, 0, 1000, 1000}; !slices.Equal(rx, want) {
+		t.Fatalf("eth0 rx rates = %v, want %v without a spike", rx, want)
+	}
+
+	// 32 位计数器回绕不是重置
+	resets = nil
+	n.sample(snapshot("eth1", 4294967000, 0), snapshot("eth1", 100, 0), sampleTiming{elapsed: time.Second})
+	if len(resets) != 0 {
+		t.Fatalf("resets after a 32-bit wrap = %q, want none", resets)
+	}
+
+	// 2 GiB 到 4 GiB 之间的计数器变小到远处时是重置, 速率不出现尖峰
+	data := n.sample(snapshot("eth1", 3<<30, 0), snapshot("eth1", 1000, 0), sampleTiming{elapsed: time.Second})
+	if !slices.Equal(resets, []string{"eth1"}) || data.PerInterface["eth1"].BytesRx != 0 {
+		t.Fatalf("resets = %q, eth1 rx = %d after a reset from 3 GiB; want eth1 and 0", resets, data.PerInterface["eth1"].BytesRx)
+	}
+}
@@ -0,0 +1,29 @@
+package mproc
+
+// WithNameResolver 用 resolve 的返回值代替 /proc/net/dev 中的接口名, 例如把容器重启后变化的 veth 名映射到 Pod 名;
+// 在 WithInterfaces 过滤之前应用, 过滤、汇总和输出都使用映射后的名称. 多个接口映射到同一个名称时计数器相加,
+// 返回空字符串时丢弃该接口. 映射后的名称在 sysfs 中通常不存在, 依赖 sysfs 的选项 (例如 WithOnlyUp) 按原名查找会失败
+func WithNameResolver(resolve func(raw string) string) netDevOpts {
+	return func(t *netDev) {
+		t.args.NameResolver = resolve
+	}
+}
+
+// resolveNames 按 NameResolver 重命名 stats 中的接口后按 Interfaces 过滤
+func (n *netDev) resolveNames(stats map[string]TsNetDev) map[string]TsNetDev {
+	filter := n.args.filter()
+	resolved := make(map[string]TsNetDev, len(stats))
+	for raw, s := range stats {
+		name := n.args.NameResolver(raw)
+		if name == "" || filter != nil && !filter(name) {
+			continue
+		}
+		s.Name = name
+		if existing, ok := resolved[name]; ok {
+			s.Receive = addNetDevInfo(existing.Receive, s.Receive)
+			s.Transmit = addNetDevInfo(existing.Transmit, s.Transmit)
+		}
+		resolved[name] = s
+	}
+	return resolved
+}
@@ -0,0 +1,47 @@
+package mproc
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithNameResolver(t *testing.T) {
+	pods := map[string]string{"veth1a2b": "pod-web", "veth3c4d": "pod-db", "veth5e6f": "pod-web"}
+	resolve := func(raw string) string {
+		if strings.HasPrefix(raw, "veth") {
+			return pods[raw] // 不认识的 veth 返回空字符串, 被丢弃
+		}
+		return raw
+	}
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 1, 2, "veth1a2b", 100, 10, "veth3c4d", 200, 20, "veth5e6f", 300, 30, "veth9999", 7, 7))
+
+	// 过滤使用映射后的名称, pod-web 的两个 veth 计数器相加
+	n := &netDev{args: &netDevArgs{Path: path}}
+	WithNameResolver(resolve)(n)
+	WithInterfaces("pod-web", "pod-db")(n)
+	stats, _, err := n.readNetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Sorted(maps.Keys(stats)); !slices.Equal(got, []string{"pod-db", "pod-web"}) {
+		t.Fatalf("interfaces = %v, want pod-db and pod-web", got)
+	}
+	if web := stats["pod-web"]; web.Name != "pod-web" || web.Receive.Bytes != 400 || web.Transmit.Bytes != 40 {
+		t.Fatalf("pod-web = %+v, want 400/40", web)
+	}
+
+	// 数据源同样使用映射后的名称
+	n = &netDev{args: &netDevArgs{}, source: NewMockSource(snapshot("veth3c4d", 5, 6, "lo", 1, 1))}
+	WithNameResolver(resolve)(n)
+	stats, _, err = n.readNetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Sorted(maps.Keys(stats)); !slices.Equal(got, []string{"lo", "pod-db"}) || stats["pod-db"].Receive.Bytes != 5 {
+		t.Fatalf("source stats = %+v, want lo and pod-db", stats)
+	}
+}
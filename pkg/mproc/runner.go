@@ -0,0 +1,89 @@
+package mproc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrProcessExited NewNetDevForPID 监控的进程已经退出
+var ErrProcessExited = errors.New("monitored process exited")
+
+// ErrReadFailed 连续读取失败的次数达到 WithMaxReadErrors 设置的上限
+var ErrReadFailed = errors.New("read failed")
+
+// Monitor Runner 管理的监控, NewNetDev 返回的监控实现了这个接口
+type Monitor interface {
+	Done() <-chan struct{} // 采样协程退出后关闭
+	Err() error            // 采样协程因错误退出的原因, 正常关闭或仍在运行时为 nil
+	Close()
+}
+
+// Runner 同时运行多个监控, 任一监控因错误退出时关闭其他监控
+type Runner struct {
+	mu       sync.Mutex
+	monitors []Monitor
+}
+
+// NewRunner 创建管理 monitors 的 Runner
+func NewRunner(monitors ...Monitor) *Runner {
+	return &Runner{monitors: monitors}
+}
+
+// Add 添加监控, 应在 Run 之前调用
+func (r *Runner) Add(m Monitor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.monitors = append(r.monitors, m)
+}
+
+// Run 等待所有监控退出, 返回第一个错误; ctx 取消或任一监控出错时关闭所有监控.
+// 没有错误退出 (例如 WithMaxLifetime 到期) 的监控不影响其他监控
+func (r *Runner) Run(ctx context.Context) error {
+	r.mu.Lock()
+	monitors := append([]Monitor(nil), r.monitors...)
+	r.mu.Unlock()
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, m := range monitors {
+		g.Go(func() error {
+			select {
+			case <-m.Done():
+				return m.Err()
+			case <-ctx.Done():
+				m.Close()
+				return nil
+			}
+		})
+	}
+	return g.Wait()
+}
+
+// Done 返回采样协程退出后关闭的通道
+func (t *netDev) Done() <-chan struct{} {
+	return t.stopped
+}
+
+// Err 返回采样协程因错误退出的原因, 例如 ErrProcessExited、ErrReadFailed; 正常关闭或仍在运行时返回 nil
+func (t *netDev) Err() error {
+	t.fatalMu.Lock()
+	defer t.fatalMu.Unlock()
+	return t.fatal
+}
+
+// Wait 等待采样协程退出, 返回 Err; 与 Close 一起使用时可以得到监控停止的原因
+func (t *netDev) Wait() error {
+	<-t.stopped
+	return t.Err()
+}
+
+// fail 记录采样协程退出的原因, 只保留第一个错误
+func (t *netDev) fail(err error) {
+	t.fatalMu.Lock()
+	defer t.fatalMu.Unlock()
+	if t.fatal == nil {
+		t.fatal = err
+	}
+}
@@ -0,0 +1,103 @@
+package mproc
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, fixtureNetDev)
+	healthy, err := NewNetDev("healthy", 20*time.Millisecond, WithPath(path), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer healthy.Close()
+
+	// 进程退出后 /proc/<pid>/net/dev 消失, 监控以 ErrProcessExited 退出
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip(err)
+	}
+	exited, err := NewNetDevForPID(cmd.Process.Pid, "exited", 20*time.Millisecond, WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- NewRunner(healthy, exited).Run(context.Background()) }()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrProcessExited) {
+			t.Fatalf("Run = %v, want ErrProcessExited", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a monitor failed")
+	}
+	select {
+	case <-healthy.Done():
+	default:
+		t.Fatal("healthy monitor was not closed")
+	}
+	if healthy.Err() != nil {
+		t.Fatalf("healthy.Err = %v, want nil after cancellation", healthy.Err())
+	}
+}
+
+func TestRunnerContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, fixtureNetDev)
+	n, err := NewNetDev("a", 20*time.Millisecond, WithPath(path), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRunner()
+	r.Add(n)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("Run = %v, want nil after cancel", err)
+	}
+	<-n.Done()
+}
+
+// failingSource 每次读取都失败
+type failingSource struct{ reads atomic.Int64 }
+
+func (s *failingSource) Read() (map[string]TsNetDev, error) {
+	s.reads.Add(1)
+	return nil, errUnavailable
+}
+
+var errUnavailable = errors.New("unavailable")
+
+func TestMaxReadErrors(t *testing.T) {
+	source := &failingSource{}
+	n, err := NewNetDev("failing", 10*time.Millisecond, WithStatsSource(source), WithMaxReadErrors(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- n.Wait() }()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrReadFailed) || !errors.Is(err, errUnavailable) {
+			t.Fatalf("Wait = %v, want ErrReadFailed wrapping the read error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor did not stop after the retry budget")
+	}
+	if got := source.reads.Load(); got != 3 {
+		t.Fatalf("reads = %d, want 3", got)
+	}
+	if !errors.Is(n.Err(), ErrReadFailed) {
+		t.Fatalf("Err = %v after Wait", n.Err())
+	}
+}
@@ -0,0 +1,28 @@
+package mproc
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// sdNotify 向 $NOTIFY_SOCKET 发送 systemd 通知, 未设置该变量时不做任何事
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	// 以 @ 开头的是抽象命名空间套接字
+	if strings.HasPrefix(addr, "@") {
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,52 @@
+package mproc
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSystemdNotify(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sd") // t.TempDir 可能超出 unix 套接字路径长度的限制
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", addr)
+
+	n := &netDev{args: &netDevArgs{SystemdNotify: true}}
+	n.notifySystemd()
+	n.notifySystemd()
+
+	var got []string
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for len(got) < 3 {
+		m, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("read notification: %v (got %q)", err, got)
+		}
+		got = append(got, string(buf[:m]))
+	}
+	want := []string{"READY=1", "WATCHDOG=1", "WATCHDOG=1"} // READY 只在第一次采样后发送
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("notifications = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSdNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatalf("sdNotify without NOTIFY_SOCKET = %v, want nil", err)
+	}
+}
@@ -0,0 +1,121 @@
+package mproc
+
+import (
+	"sync"
+	"time"
+)
+
+// sharedReads 进程内所有启用 WithSharedRead 的监控共用的读取缓存, 键为文件路径
+var sharedReads = &sharedReadCache{entries: make(map[string]*sharedRead)}
+
+// sharedReadCache 按路径缓存最近一次成功读取的内容, 读取失败不缓存
+type sharedReadCache struct {
+	mu      sync.Mutex
+	entries map[string]*sharedRead
+}
+
+// sharedRead 一个路径的缓存, 同一路径同时只有一次读取, 其他监控等待并复用结果
+type sharedRead struct {
+	refs int // 使用该路径的监控数, 由 sharedReadCache.mu 保护, 为 0 时从缓存中删除
+
+	mu   sync.Mutex
+	last netDevRead
+}
+
+// acquire 返回 path 的缓存并增加引用, 监控退出时调用 release
+func (c *sharedReadCache) acquire(path string) *sharedRead {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok {
+		e = &sharedRead{}
+		c.entries[path] = e
+	}
+	e.refs++
+	return e
+}
+
+// release 减少 path 的引用, 最后一个使用的监控释放时删除缓存
+func (c *sharedReadCache) release(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[path]; ok {
+		if e.refs--; e.refs <= 0 {
+			delete(c.entries, path)
+		}
+	}
+}
+
+// read 返回 ttl 内且晚于 after 的缓存, 否则调用 read 重新读取; 返回的 at 为实际读取的时间, 可能早于本次调用.
+// 要求晚于 after 保证同一个监控不会两次拿到同一次读取, 否则会得到零增量和下一次加倍的速率
+func (e *sharedRead) read(ttl time.Duration, after time.Time, read func() netDevRead) netDevRead {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if last := e.last; last.at.After(after) && time.Since(last.at) < ttl {
+		return last
+	}
+	r := read()
+	if r.err == nil {
+		e.last = r // 内容是副本, 各监控只读, 可以共享
+	}
+	return r
+}
+
+// sharedReader 一个监控对一个路径的共享读取, 记录上一次拿到的读取时间
+type sharedReader struct {
+	entry *sharedRead
+	read  func() netDevRead
+	last  time.Time
+}
+
+// WithSharedRead 同一进程中多个监控读取同一个文件时, ttl 内只读取一次并共享内容, 适合大量监控同一个 /proc/net/dev.
+// ttl <= 0 时为采样间隔的一半; 共享的读取最多早 ttl, 采样时间为实际读取的时间, 速率按各自拿到的两次读取之间的时间计算
+func WithSharedRead(ttl time.Duration) netDevOpts {
+	return func(t *netDev) {
+		t.args.SharedRead = true
+		t.args.SharedReadTTL = ttl
+	}
+}
+
+// sharedReader 用共享缓存包装 read, 只在采样协程中或启动前调用
+func (n *netDev) sharedReader(path string, read func() netDevRead) func() netDevRead {
+	if n.shared == nil {
+		n.shared = make(map[string]*sharedReader)
+	}
+	s, ok := n.shared[path]
+	if !ok {
+		s = &sharedReader{entry: sharedReads.acquire(path)}
+		n.shared[path] = s
+	}
+	s.read = read
+	ttl := n.args.SharedReadTTL
+	if ttl <= 0 {
+		ttl = n.args.Interval / 2
+	}
+	return func() netDevRead {
+		r := s.entry.read(ttl, s.last, s.read)
+		if r.err == nil {
+			s.last = r.at
+		}
+		return r
+	}
+}
+
+// sharedReadTime 返回 WithSharedRead 最近一次拿到的读取时间, 多个路径时取最晚的; 没有共享读取时返回零值
+func (n *netDev) sharedReadTime() time.Time {
+	var at time.Time
+	for _, s := range n.shared {
+		if s.last.After(at) {
+			at = s.last
+		}
+	}
+	return at
+}
+
+// releaseShared 监控退出时释放共享读取的缓存
+func (n *netDev) releaseShared() {
+	for path := range n.shared {
+		sharedReads.release(path)
+	}
+	n.shared = nil
+}
@@ -0,0 +1,128 @@
+package mproc
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithSharedRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 1000, 0))
+
+	newMonitor := func() *netDev {
+		n := &netDev{args: &netDevArgs{Path: path, Interval: time.Second}}
+		WithSharedRead(time.Hour)(n)
+		return n
+	}
+	rx := func(n *netDev) int64 {
+		t.Helper()
+		stats, _, err := n.readNetDev()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stats["eth0"].Receive.Bytes
+	}
+	a, b := newMonitor(), newMonitor()
+
+	if got := rx(a); got != 1000 {
+		t.Fatalf("a: rx = %d, want 1000", got)
+	}
+	// ttl 内 b 复用 a 的读取, 看不到文件的变化
+	writeFile(t, path, netDevFile("eth0", 2000, 0))
+	if got := rx(b); got != 1000 {
+		t.Fatalf("b: rx = %d, want the shared read 1000", got)
+	}
+	// a 已经用过缓存中的读取, 必须重新读取, 否则增量为 0
+	if got := rx(a); got != 2000 {
+		t.Fatalf("a: rx = %d, want a fresh read 2000", got)
+	}
+	writeFile(t, path, netDevFile("eth0", 3000, 0))
+	if got := rx(b); got != 2000 {
+		t.Fatalf("b: rx = %d, want the read shared by a 2000", got)
+	}
+
+	// 没有启用的监控不受缓存影响
+	plain := &netDev{args: &netDevArgs{Path: path}}
+	if stats, _, err := plain.readNetDev(); err != nil || stats["eth0"].Receive.Bytes != 3000 {
+		t.Fatalf("plain: stats = %+v, err = %v; want rx 3000", stats, err)
+	}
+}
+
+func TestSharedReadTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 1000, 0))
+	a := &netDev{args: &netDevArgs{Path: path, Interval: 20 * time.Millisecond, SharedRead: true}}
+	b := &netDev{args: &netDevArgs{Path: path, Interval: 20 * time.Millisecond, SharedRead: true}}
+	if _, _, err := a.readNetDev(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, netDevFile("eth0", 2000, 0))
+	time.Sleep(20 * time.Millisecond) // 超过默认的 ttl (间隔的一半)
+	stats, _, err := b.readNetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stats["eth0"].Receive.Bytes; got != 2000 {
+		t.Fatalf("rx = %d, want 2000 after the ttl expired", got)
+	}
+}
+
+// 复用的读取以实际读取的时间作为采样时间, 而不是拿到缓存的时间
+func TestSharedReadTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 1000, 0))
+	a := &netDev{args: &netDevArgs{Path: path, Interval: time.Second}}
+	b := &netDev{args: &netDevArgs{Path: path, Interval: time.Second}}
+	WithSharedRead(time.Hour)(a)
+	WithSharedRead(time.Hour)(b)
+	defer a.releaseShared()
+	defer b.releaseShared()
+
+	if _, _, err := a.readNetDev(); err != nil {
+		t.Fatal(err)
+	}
+	readAt := a.readTime()
+	time.Sleep(20 * time.Millisecond)
+	if _, _, err := b.readNetDev(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.readTime(); !got.Equal(readAt) {
+		t.Fatalf("b read time = %v, want the shared read at %v", got, readAt)
+	}
+}
+
+// 最后一个使用路径的监控释放后缓存被删除
+func TestSharedReadRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path, netDevFile("eth0", 1000, 0))
+	cached := func() bool {
+		sharedReads.mu.Lock()
+		defer sharedReads.mu.Unlock()
+		_, ok := sharedReads.entries[path]
+		return ok
+	}
+
+	var monitors []*netDev
+	for range 2 {
+		n, err := NewNetDev("shared", time.Hour, WithPath(path), WithSharedRead(0), WithCallback(func(TsCallData) {}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		monitors = append(monitors, n)
+	}
+	n := monitors[0]
+	if rates := n.RateSince(); rates.Name != "shared" || !cached() {
+		t.Fatalf("RateSince = %+v, cached = %v; want a shared read", rates, cached())
+	}
+	monitors[1].RateSince()
+
+	monitors[0].Close()
+	if !cached() {
+		t.Fatal("cache removed while another monitor still uses the path")
+	}
+	monitors[1].Close()
+	if cached() {
+		t.Fatal("cache kept after every monitor closed")
+	}
+}
@@ -0,0 +1,123 @@
+package mproc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mu      sync.Mutex
+	samples []TsCallData
+	closed  bool
+	err     error
+}
+
+func (s *fakeSink) Write(data TsCallData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, data)
+	return s.err
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// 一个输出失败不影响其他输出, 关闭监控时所有输出都被关闭
+func TestWithSink(t *testing.T) {
+	failing := &fakeSink{err: errors.New("unavailable")}
+	sink := &fakeSink{}
+	n := newFIFONetDev(t, time.Second, WithSink(failing), WithSink(sink))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0))
+	n.next()
+	n.feed(netDevFile("eth0", 3000, 0))
+	n.next()
+	n.feed(netDevFile("eth0", 3000, 0)) // 下一次读取在上一个采样写入输出之后
+	n.Close()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.samples) < 2 || sink.samples[0].BytesRx != 1000 || sink.samples[1].BytesRx != 2000 {
+		t.Fatalf("sink samples = %+v, want 1000 then 2000", sink.samples)
+	}
+	if !sink.closed || !failing.closed {
+		t.Fatal("sinks not closed on Close")
+	}
+}
+
+// rollbackSink 记录撤销的 fakeSink
+type rollbackSink struct {
+	fakeSink
+	rolledBack []TsCallData
+}
+
+func (s *rollbackSink) Rollback(data TsCallData) error {
+	s.rolledBack = append(s.rolledBack, data)
+	return nil
+}
+
+func TestWithSinkPolicy(t *testing.T) {
+	data := TsCallData{Name: "policy", BytesRx: 1000}
+	setup := func(policy SinkPolicy) (*netDev, *rollbackSink, *fakeSink, *fakeSink, map[Sink][]error) {
+		first, failing, last := &rollbackSink{}, &fakeSink{err: errors.New("disk full")}, &fakeSink{}
+		reported := map[Sink][]error{}
+		n := &netDev{args: &netDevArgs{}}
+		for _, opt := range []netDevOpts{WithSink(first), WithSink(failing), WithSink(last), WithSinkPolicy(policy),
+			WithSinkErrorHandler(func(s Sink, err error) { reported[s] = append(reported[s], err) })} {
+			opt(n)
+		}
+		return n, first, failing, last, reported
+	}
+
+	// 默认: 失败的输出不影响其他输出, 错误按输出报告
+	n, first, failing, last, reported := setup(SinkBestEffort)
+	n.writeSinks(data)
+	if len(first.samples) != 1 || len(last.samples) != 1 || len(failing.samples) != 1 || len(first.rolledBack) != 0 {
+		t.Fatalf("best effort: writes = %d/%d/%d, rollbacks = %d", len(first.samples), len(failing.samples), len(last.samples), len(first.rolledBack))
+	}
+	if len(reported) != 1 || len(reported[failing]) != 1 {
+		t.Fatalf("best effort: reported = %v, want one error for the failing sink", reported)
+	}
+
+	// 全部或没有: 重试一次, 之后的输出不写入, 已写入的输出被撤销
+	n, first, failing, last, reported = setup(SinkAllOrNothing)
+	n.writeSinks(data)
+	if len(failing.samples) != 2 || len(last.samples) != 0 {
+		t.Fatalf("all or nothing: failing writes = %d, last writes = %d; want 2 and 0", len(failing.samples), len(last.samples))
+	}
+	if len(first.rolledBack) != 1 || first.rolledBack[0].Name != "policy" {
+		t.Fatalf("all or nothing: rollbacks = %+v, want the sample", first.rolledBack)
+	}
+	if len(reported) != 1 || len(reported[failing]) != 1 {
+		t.Fatalf("all or nothing: reported = %v, want one error for the failing sink", reported)
+	}
+
+	// 重试成功时所有输出都写入
+	n, first, failing, last, _ = setup(SinkAllOrNothing)
+	n.sinks[1] = &flakySink{fakeSink: failing}
+	n.writeSinks(data)
+	if len(last.samples) != 1 || len(first.rolledBack) != 0 {
+		t.Fatalf("retry: last writes = %d, rollbacks = %d; want 1 and 0", len(last.samples), len(first.rolledBack))
+	}
+}
+
+// flakySink 第一次写入失败
+type flakySink struct {
+	*fakeSink
+	failed bool
+}
+
+func (s *flakySink) Write(data TsCallData) error {
+	if !s.failed {
+		s.failed = true
+		return errors.New("connection reset")
+	}
+	s.fakeSink.err = nil
+	return s.fakeSink.Write(data)
+}
@@ -0,0 +1,57 @@
+package mproc
+
+import "fmt"
+
+// SinkPolicy 有多个输出时一个输出写入失败的处理方式
+//
+// Graphite、remote-write、unix socket 等网络输出的 Write 只把采样放入队列, 只有队列已满时才返回错误;
+// 之后在发送协程中的连接或发送失败只记录日志, SinkAllOrNothing 看不到, 也不会调用 WithSinkErrorHandler
+type SinkPolicy int
+
+const (
+	SinkBestEffort   SinkPolicy = iota // 各输出独立写入, 一个输出失败不影响其他输出 (默认)
+	SinkAllOrNothing                   // 按顺序写入, 失败的输出重试一次, 仍失败时不写入之后的输出, 并撤销已写入的输出
+)
+
+// SinkRollback 可以撤销一次写入的输出, 由 SinkAllOrNothing 在之后的输出失败时调用;
+// 没有实现的输出 (例如已经放入发送队列的网络输出) 无法撤销, 采样保留在其中
+type SinkRollback interface {
+	Rollback(data TsCallData) error
+}
+
+// WithSinkPolicy 设置有多个输出时的写入方式, 默认为 SinkBestEffort
+func WithSinkPolicy(policy SinkPolicy) netDevOpts {
+	return func(t *netDev) {
+		t.args.SinkPolicy = policy
+	}
+}
+
+// WithSinkErrorHandler 在采样协程中以出错的输出和错误调用 handler, 代替记录日志;
+// SinkAllOrNothing 时只报告最终失败的输出和撤销失败的输出. 网络输出只报告队列已满, 发送失败见 SinkPolicy
+func WithSinkErrorHandler(handler func(s Sink, err error)) netDevOpts {
+	return func(t *netDev) {
+		t.args.SinkErrorHandler = handler
+	}
+}
+
+// writeSinksAtomic 按 SinkAllOrNothing 写入, 要么所有输出都写入, 要么在可以撤销的范围内都不写入
+func (n *netDev) writeSinksAtomic(data TsCallData) {
+	for i, s := range n.sinks {
+		err := s.Write(data)
+		if err == nil {
+			continue
+		}
+		if err = s.Write(data); err == nil { // 临时错误 (例如连接刚断开) 重试一次
+			continue
+		}
+		n.sinkError(s, err)
+		for _, written := range n.sinks[:i] {
+			if r, ok := written.(SinkRollback); ok {
+				if err := r.Rollback(data); err != nil {
+					n.sinkError(written, fmt.Errorf("rollback: %w", err))
+				}
+			}
+		}
+		return
+	}
+}
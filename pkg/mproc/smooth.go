@@ -0,0 +1,57 @@
+package mproc
+
+// ewma 指数加权移动平均, 第一个值直接作为初始平均值
+type ewma struct {
+	value float64
+	ok    bool
+}
+
+func (e *ewma) add(alpha, v float64) float64 {
+	if !e.ok {
+		e.value, e.ok = v, true
+	} else {
+		e.value += alpha * (v - e.value)
+	}
+	return e.value
+}
+
+type ewmaPair struct {
+	rx, tx ewma
+}
+
+// smoother 分别平滑汇总速率和每个接口的速率
+type smoother struct {
+	alpha  float64
+	total  ewmaPair
+	ifaces map[string]*ewmaPair
+}
+
+// newSmoother 创建平滑器, alpha 超出 (0, 1] 时按 1 处理, 即不平滑
+func newSmoother(alpha float64) *smoother {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 1
+	}
+	return &smoother{alpha: alpha, ifaces: make(map[string]*ewmaPair)}
+}
+
+// apply 计算平滑后的速率; 本次没有出现的接口丢弃其平均值, 重新出现时从头开始
+func (s *smoother) apply(data *TsCallData) {
+	data.SmoothedRx = s.total.rx.add(s.alpha, data.BytesRxF)
+	data.SmoothedTx = s.total.tx.add(s.alpha, data.BytesTxF)
+
+	for name := range s.ifaces {
+		if _, ok := data.PerInterface[name]; !ok {
+			delete(s.ifaces, name)
+		}
+	}
+	for name, rate := range data.PerInterface {
+		p, ok := s.ifaces[name]
+		if !ok {
+			p = &ewmaPair{}
+			s.ifaces[name] = p
+		}
+		rate.SmoothedRx = p.rx.add(s.alpha, rate.BytesRxF)
+		rate.SmoothedTx = p.tx.add(s.alpha, rate.BytesTxF)
+		data.PerInterface[name] = rate
+	}
+}
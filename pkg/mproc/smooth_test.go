@@ -0,0 +1,54 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSmootherPerInterface(t *testing.T) {
+	s := newSmoother(0.5)
+	sample := func(rx0, rx1 float64) TsCallData {
+		data := TsCallData{
+			BytesRxF: rx0 + rx1,
+			PerInterface: map[string]TsInterfaceRate{
+				"eth0": {BytesRxF: rx0},
+				"eth1": {BytesRxF: rx1},
+			},
+		}
+		s.apply(&data)
+		return data
+	}
+
+	sample(100, 1000)
+	data := sample(300, 1000)
+	if data.SmoothedRx != 1200 {
+		t.Fatalf("aggregate SmoothedRx = %v, want 1200", data.SmoothedRx)
+	}
+	if got := data.PerInterface["eth0"].SmoothedRx; got != 200 {
+		t.Fatalf("eth0 SmoothedRx = %v, want 200", got)
+	}
+	if got := data.PerInterface["eth1"].SmoothedRx; got != 1000 {
+		t.Fatalf("eth1 SmoothedRx = %v, want 1000", got)
+	}
+
+	// eth1 消失后再出现, 平均值重新开始
+	only := TsCallData{PerInterface: map[string]TsInterfaceRate{"eth0": {BytesRxF: 200}}}
+	s.apply(&only)
+	data = sample(200, 4000)
+	if got := data.PerInterface["eth1"].SmoothedRx; got != 4000 {
+		t.Fatalf("eth1 after reappearing: SmoothedRx = %v, want 4000", got)
+	}
+}
+
+func TestPerInterface(t *testing.T) {
+	n := newFIFONetDev(t, time.Second, WithSmoothing(0.5))
+	n.feed(netDevFile("eth0", 0, 0, "eth1", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 10, "eth1", 0, 0, "eth2", 500, 0))
+	data := n.next()
+	if len(data.PerInterface) != 2 {
+		t.Fatalf("PerInterface = %v, want eth0 and eth1 only, eth2 is a new baseline", data.PerInterface)
+	}
+	if r := data.PerInterface["eth0"]; r.BytesRx != 1000 || r.BytesTx != 10 || r.SmoothedRx != 1000 {
+		t.Fatalf("eth0 = %+v, want 1000/10 smoothed to 1000", r)
+	}
+}
@@ -0,0 +1,26 @@
+package mproc
+
+// StatsSource 接口计数器的来源, 设置后代替读取 /proc/net/dev
+type StatsSource interface {
+	Read() (map[string]TsNetDev, error)
+}
+
+// BPFMapDecoder 按固定 (pinned) 的路径读取并解码 eBPF map, 由调用方根据 map 的键值布局实现.
+// pinned map 不能用 read(2) 读取 (返回 EINVAL), 需要通过 bpf 系统调用访问, 例如 ebpf.LoadPinnedMap(path, nil)
+type BPFMapDecoder func(path string) (map[string]TsNetDev, error)
+
+type bpfMapSource struct {
+	path   string
+	decode BPFMapDecoder
+}
+
+// NewBPFMapSource 从固定 (pinned) 的 eBPF map 读取计数器, 例如按 socket 或 cgroup 统计的流量
+//
+// 包本身不依赖 eBPF 加载库, 每次读取以 path 调用 decode, 打开 map 和遍历条目都由 decode 完成
+func NewBPFMapSource(path string, decode BPFMapDecoder) StatsSource {
+	return &bpfMapSource{path: path, decode: decode}
+}
+
+func (s *bpfMapSource) Read() (map[string]TsNetDev, error) {
+	return s.decode(s.path)
+}
@@ -0,0 +1,98 @@
+package mproc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// decodeFakeMap 代替 eBPF 加载库读取测试用的 map 文件: 每条记录为 16 字节的接口名和两个小端 uint64 (rx, tx)
+func decodeFakeMap(path string) (map[string]TsNetDev, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	stats := map[string]TsNetDev{}
+	for {
+		var rec struct {
+			Name   [16]byte
+			Rx, Tx uint64
+		}
+		if err := binary.Read(r, binary.LittleEndian, &rec); err == io.EOF {
+			return stats, nil
+		} else if err != nil {
+			return nil, err
+		}
+		name := string(rec.Name[:clen(rec.Name[:])])
+		stats[name] = TsNetDev{
+			Name:     name,
+			Receive:  TsNetDevInfo{Bytes: int64(rec.Rx)},
+			Transmit: TsNetDevInfo{Bytes: int64(rec.Tx)},
+		}
+	}
+}
+
+func clen(b []byte) int {
+	for i, c := range b {
+		if c == 0 {
+			return i
+		}
+	}
+	return len(b)
+}
+
+func fakeMap(name string, rx, tx uint64) string {
+	b := make([]byte, 32)
+	copy(b, name)
+	binary.LittleEndian.PutUint64(b[16:], rx)
+	binary.LittleEndian.PutUint64(b[24:], tx)
+	return string(b)
+}
+
+func TestBPFMapSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	writeFile(t, path, fakeMap("cgroup-a", 1000, 2000))
+	stats, err := NewBPFMapSource(path, decodeFakeMap).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := stats["cgroup-a"]; s.Receive.Bytes != 1000 || s.Transmit.Bytes != 2000 {
+		t.Fatalf("stats = %+v, want cgroup-a 1000/2000", stats)
+	}
+}
+
+// decode 以固定的路径调用, 由调用方的加载库打开 map
+func TestBPFMapSourcePath(t *testing.T) {
+	var got []string
+	src := NewBPFMapSource("/sys/fs/bpf/netstat", func(path string) (map[string]TsNetDev, error) {
+		got = append(got, path)
+		return map[string]TsNetDev{"cgroup-a": {Name: "cgroup-a"}}, nil
+	})
+	if _, err := src.Read(); err != nil || len(got) != 1 || got[0] != "/sys/fs/bpf/netstat" {
+		t.Fatalf("decode called with %q, err = %v; want the pinned path", got, err)
+	}
+}
+
+func TestWithStatsSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	writeFile(t, path, fakeMap("cgroup-a", 0, 0))
+	n := &netDev{args: &netDevArgs{Path: "/nonexistent"}, source: NewBPFMapSource(path, decodeFakeMap)}
+	before, _, err := n.readNetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, fakeMap("cgroup-a", 3000, 0))
+	after, _, err := n.readNetDev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := Diff(before, after, time.Second); data.BytesRx != 3000 {
+		t.Fatalf("BytesRx = %d, want 3000", data.BytesRx)
+	}
+}
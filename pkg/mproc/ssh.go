@@ -0,0 +1,87 @@
+package mproc
+
+import (
+	"bytes"
+	"sync"
+)
+
+// sshNetDevCommand 在远程主机上执行的命令
+const sshNetDevCommand = "cat /proc/net/dev"
+
+// SSHConn 到远程主机的连接, Run 执行一条命令并返回标准输出
+//
+// 包本身不依赖 SSH 库, 使用 golang.org/x/crypto/ssh 时每次 Run 在 *ssh.Client 上新建一个会话:
+//
+//	session, err := client.NewSession()
+//	if err != nil {
+//		return nil, err
+//	}
+//	defer session.Close()
+//	return session.Output(cmd)
+type SSHConn interface {
+	Run(cmd string) ([]byte, error)
+	Close() error
+}
+
+// SSHDialer 建立到远程主机的连接, 第一次读取和连接断开后调用
+type SSHDialer func() (SSHConn, error)
+
+// SSHSource 通过 SSH 读取远程主机 /proc/net/dev 的 StatsSource, 不需要在远程主机上部署程序
+type SSHSource struct {
+	dial SSHDialer
+	cmd  string
+
+	mu   sync.Mutex
+	conn SSHConn
+}
+
+// NewSSHSource 创建通过 dial 建立的连接读取计数器的数据源; 命令执行失败时断开连接,
+// 在同一次读取中重新连接并重试一次, 仍然失败时返回错误, 下一次读取再重新连接.
+// 监控关闭时不关闭连接, 使用完后调用 Close
+func NewSSHSource(dial SSHDialer) *SSHSource {
+	return &SSHSource{dial: dial, cmd: sshNetDevCommand}
+}
+
+// Read 在远程主机上执行 cat /proc/net/dev 并解析输出
+func (s *SSHSource) Read() (map[string]TsNetDev, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reused := s.conn != nil
+	out, err := s.run()
+	if err != nil && reused {
+		out, err = s.run() // 复用的连接可能已经断开
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(out), nil)
+}
+
+// run 按需建立连接后执行命令, 失败时关闭连接
+func (s *SSHSource) run() ([]byte, error) {
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+	}
+	out, err := s.conn.Run(s.cmd)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return out, err
+}
+
+// Close 关闭当前的连接, 之后的读取会重新连接
+func (s *SSHSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,80 @@
+package mproc
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeSSHConn 返回固定内容的连接, broken 后每次执行都失败
+type fakeSSHConn struct {
+	output string
+	broken bool
+	closed bool
+	cmds   []string
+}
+
+func (c *fakeSSHConn) Run(cmd string) ([]byte, error) {
+	c.cmds = append(c.cmds, cmd)
+	if c.broken {
+		return nil, errors.New("connection lost")
+	}
+	return []byte(c.output), nil
+}
+
+func (c *fakeSSHConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSSHSource(t *testing.T) {
+	var conns []*fakeSSHConn
+	output := fixtureNetDev
+	dialErr := error(nil)
+	source := NewSSHSource(func() (SSHConn, error) {
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		c := &fakeSSHConn{output: output}
+		conns = append(conns, c)
+		return c, nil
+	})
+
+	stats, err := source.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["eth0"].Receive.Bytes != 1215645 || len(conns) != 1 || conns[0].cmds[0] != "cat /proc/net/dev" {
+		t.Fatalf("stats = %+v after %d dials", stats, len(conns))
+	}
+	// 连接复用
+	source.Read()
+	if len(conns) != 1 {
+		t.Fatalf("dials = %d, want the connection reused", len(conns))
+	}
+
+	// 连接断开后在同一次读取中重新连接
+	conns[0].broken = true
+	output = netDevFile("eth0", 2000, 3000)
+	stats, err = source.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["eth0"].Receive.Bytes != 2000 || len(conns) != 2 || !conns[0].closed {
+		t.Fatalf("stats = %+v after %d dials, want a fresh connection", stats, len(conns))
+	}
+
+	// 远程主机不可达时返回错误, 恢复后重新连接
+	conns[1].broken = true
+	dialErr = errors.New("no route to host")
+	if _, err := source.Read(); !errors.Is(err, dialErr) {
+		t.Fatalf("err = %v, want the dial error", err)
+	}
+	dialErr = nil
+	if _, err := source.Read(); err != nil || len(conns) != 3 {
+		t.Fatalf("err = %v after %d dials, want a reconnect", err, len(conns))
+	}
+
+	if err := source.Close(); err != nil || !conns[2].closed {
+		t.Fatalf("Close = %v, closed = %v", err, conns[2].closed)
+	}
+}
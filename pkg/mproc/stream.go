@@ -0,0 +1,82 @@
+package mproc
+
+import (
+	"maps"
+	"sync"
+)
+
+// streamBuffer 每个订阅通道的缓冲, 消费者跟不上时丢弃新的采样而不阻塞采样协程
+const streamBuffer = 16
+
+// streams 采样的订阅者, 零值可用; 监控退出时关闭所有通道
+type streams struct {
+	mu        sync.Mutex
+	closed    bool
+	aggregate []chan TsCallData
+	perIface  []chan map[string]TsInterfaceRate
+}
+
+// StreamAggregate 订阅汇总速率, 每个采样发送一次, 其中不含 PerInterface; 监控关闭后通道被关闭.
+// 消费者跟不上时丢弃采样
+func (t *netDev) StreamAggregate() <-chan TsCallData {
+	ch := make(chan TsCallData, streamBuffer)
+	t.streams.mu.Lock()
+	defer t.streams.mu.Unlock()
+	if t.streams.closed {
+		close(ch)
+		return ch
+	}
+	t.streams.aggregate = append(t.streams.aggregate, ch)
+	return ch
+}
+
+// StreamPerInterface 订阅每个接口的速率, 需要 MetricPerInterface, 没有 PerInterface 的采样不发送;
+// 监控关闭后通道被关闭. 消费者跟不上时丢弃采样
+func (t *netDev) StreamPerInterface() <-chan map[string]TsInterfaceRate {
+	ch := make(chan map[string]TsInterfaceRate, streamBuffer)
+	t.streams.mu.Lock()
+	defer t.streams.mu.Unlock()
+	if t.streams.closed {
+		close(ch)
+		return ch
+	}
+	t.streams.perIface = append(t.streams.perIface, ch)
+	return ch
+}
+
+// publish 在采样协程中调用, 不会阻塞
+func (s *streams) publish(data TsCallData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.aggregate) > 0 {
+		agg := data
+		agg.PerInterface = nil
+		for _, ch := range s.aggregate {
+			select {
+			case ch <- agg:
+			default:
+			}
+		}
+	}
+	if data.PerInterface != nil {
+		for _, ch := range s.perIface {
+			select {
+			case ch <- maps.Clone(data.PerInterface): // 每个订阅者各自一份, 可以修改
+			default:
+			}
+		}
+	}
+}
+
+func (s *streams) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, ch := range s.aggregate {
+		close(ch)
+	}
+	for _, ch := range s.perIface {
+		close(ch)
+	}
+	s.aggregate, s.perIface = nil, nil
+}
@@ -0,0 +1,39 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreams(t *testing.T) {
+	n := newFIFONetDev(t, 50*time.Millisecond)
+	agg := n.StreamAggregate()
+	perIface := n.StreamPerInterface()
+	n.feed(netDevFile("eth0", 0, 0, "eth1", 0, 0))
+	n.feed(netDevFile("eth0", 1000, 0, "eth1", 0, 2000))
+	sent := n.next()
+
+	data := <-agg
+	if data.PerInterface != nil || data.BytesRx != sent.BytesRx || data.BytesTx != sent.BytesTx {
+		t.Fatalf("aggregate = %+v, want totals %d/%d without PerInterface", data, sent.BytesRx, sent.BytesTx)
+	}
+	rates := <-perIface
+	if len(rates) != 2 || rates["eth0"].BytesRx != sent.PerInterface["eth0"].BytesRx || rates["eth1"].BytesTx != sent.PerInterface["eth1"].BytesTx {
+		t.Fatalf("per-interface = %+v, want %+v", rates, sent.PerInterface)
+	}
+
+	n.Close()
+	if _, ok := <-agg; ok {
+		t.Fatal("aggregate stream still open after Close")
+	}
+	if _, ok := <-perIface; ok {
+		t.Fatal("per-interface stream still open after Close")
+	}
+	// 关闭后订阅得到已关闭的通道
+	if _, ok := <-n.StreamAggregate(); ok {
+		t.Fatal("StreamAggregate after Close returned an open channel")
+	}
+	if _, ok := <-n.StreamPerInterface(); ok {
+		t.Fatal("StreamPerInterface after Close returned an open channel")
+	}
+}
@@ -0,0 +1,49 @@
+package mproc
+
+import (
+	"sync"
+	"time"
+)
+
+// TsSummary 监控启动以来的汇总, 见 Summary
+type TsSummary struct {
+	Name    string
+	Samples int64 // 采样次数, 不包括心跳
+
+	BytesRx int64 // 观察到的接收字节总数
+	BytesTx int64 // 观察到的发送字节总数
+
+	PeakRx float64 // 单次采样的最高接收速率
+	PeakTx float64 // 单次采样的最高发送速率
+
+	Duration time.Duration // 各次采样间隔之和, 包括心跳, 用于计算平均速率
+}
+
+// summary 在采样协程中累计, Summary 可以在任意协程中读取
+type summary struct {
+	mu   sync.Mutex
+	data TsSummary
+}
+
+func (s *summary) add(data TsCallData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = data.Name
+	s.data.Duration += data.Interval
+	if data.Heartbeat {
+		return
+	}
+	var sat saturation
+	s.data.Samples++
+	s.data.BytesRx = sat.add(s.data.BytesRx, data.DeltaRx)
+	s.data.BytesTx = sat.add(s.data.BytesTx, data.DeltaTx)
+	s.data.PeakRx = max(s.data.PeakRx, data.BytesRxF)
+	s.data.PeakTx = max(s.data.PeakTx, data.BytesTxF)
+}
+
+// Summary 返回启动以来的汇总, 可以在 Close 之后调用得到最终结果
+func (t *netDev) Summary() TsSummary {
+	t.summary.mu.Lock()
+	defer t.summary.mu.Unlock()
+	return t.summary.data
+}
@@ -0,0 +1,53 @@
+package mproc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummary(t *testing.T) {
+	n := newFIFONetDev(t, 50*time.Millisecond)
+	n.feed(netDevFile("eth0", 0, 0))
+	for _, c := range [][2]int{{1000, 100}, {1500, 900}, {4000, 1000}} {
+		n.feed(netDevFile("eth0", c[0], c[1]))
+		n.next()
+	}
+	n.Close()
+
+	s := n.Summary()
+	if s.Name != "test" || s.Samples != 3 {
+		t.Fatalf("summary = %+v, want 3 samples of test", s)
+	}
+	if s.BytesRx != 4000 || s.BytesTx != 1000 {
+		t.Fatalf("totals = %d/%d, want 4000/1000", s.BytesRx, s.BytesTx)
+	}
+	// 间隔固定为 50ms, 峰值分别出现在第三次和第二次采样
+	if s.PeakRx != 2500/0.05 || s.PeakTx != 800/0.05 {
+		t.Fatalf("peaks = %v/%v, want %v/%v", s.PeakRx, s.PeakTx, 2500/0.05, 800/0.05)
+	}
+	if s.Duration != 150*time.Millisecond {
+		t.Fatalf("Duration = %v, want 150ms", s.Duration)
+	}
+}
+
+// 心跳不计入采样次数, 但其间隔计入 Duration
+func TestSummarySkipsHeartbeat(t *testing.T) {
+	n := newFIFONetDev(t, 50*time.Millisecond, WithHeartbeat(true))
+	n.feed(netDevFile("eth0", 0, 0))
+	n.next() // 首次读取的心跳
+	n.feed(netDevFile("eth0", 1000, 0))
+	n.next()
+	n.feed(netDevFile("eth0", 1000, 0))
+	if data := n.next(); !data.Heartbeat {
+		t.Fatalf("second sample = %+v, want a heartbeat", data)
+	}
+	n.Close()
+
+	s := n.Summary()
+	if s.Samples != 1 || s.BytesRx != 1000 {
+		t.Fatalf("summary = %+v, want 1 sample of 1000 bytes", s)
+	}
+	if s.Duration != 100*time.Millisecond {
+		t.Fatalf("Duration = %v, want 100ms", s.Duration)
+	}
+}
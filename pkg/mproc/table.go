@@ -0,0 +1,83 @@
+package mproc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// tableRow RenderTable 的一行
+type tableRow struct {
+	name, iface string
+	rx, tx      float64
+}
+
+// RenderTable 以类似 top 的表格输出采样, 每个接口一行, 按当前总速率从高到低排序
+//
+// 没有 PerInterface 的采样 (例如 WithMetrics 关闭了 MetricPerInterface) 以汇总速率输出一行, 接口列为 "-"
+func RenderTable(samples []TsCallData) string {
+	rows := make([]tableRow, 0, len(samples))
+	for _, s := range samples {
+		if len(s.PerInterface) == 0 {
+			rows = append(rows, tableRow{name: s.Name, iface: "-", rx: s.BytesRxF, tx: s.BytesTxF})
+			continue
+		}
+		for iface, r := range s.PerInterface {
+			rows = append(rows, tableRow{name: s.Name, iface: iface, rx: r.BytesRxF, tx: r.BytesTxF})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if a, b := rows[i].rx+rows[i].tx, rows[j].rx+rows[j].tx; a != b {
+			return a > b
+		}
+		if rows[i].name != rows[j].name {
+			return rows[i].name < rows[j].name
+		}
+		return rows[i].iface < rows[j].iface
+	})
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "NAME\tIFACE\tRX\tTX\t")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", r.name, r.iface, humanRate(r.rx), humanRate(r.tx))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// humanRate 以 1024 为进制格式化字节速率
+func humanRate(bps float64) string {
+	return formatRate(bps, 1024, iecUnits)
+}
+
+var (
+	siUnits  = []string{"B/s", "kB/s", "MB/s", "GB/s", "TB/s"}
+	iecUnits = []string{"B/s", "KiB/s", "MiB/s", "GiB/s", "TiB/s"}
+)
+
+// formatRate 以 base 为进制格式化字节速率, 不足 1 个单位时保留整数
+func formatRate(bps, base float64, units []string) string {
+	i := 0
+	for bps >= base && i < len(units)-1 {
+		bps /= base
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", bps, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", bps, units[i])
+}
+
+// HumanRxSI 以 SI 单位 (1 kB = 1000 B, 网络设备厂商的习惯) 格式化接收速率, 例如 12.3 MB/s
+func (d TsCallData) HumanRxSI() string { return formatRate(d.BytesRxF, 1000, siUnits) }
+
+// HumanRxIEC 以 IEC 单位 (1 KiB = 1024 B) 格式化接收速率, 例如 11.7 MiB/s
+func (d TsCallData) HumanRxIEC() string { return formatRate(d.BytesRxF, 1024, iecUnits) }
+
+// HumanTxSI 以 SI 单位格式化发送速率, 见 HumanRxSI
+func (d TsCallData) HumanTxSI() string { return formatRate(d.BytesTxF, 1000, siUnits) }
+
+// HumanTxIEC 以 IEC 单位格式化发送速率, 见 HumanRxIEC
+func (d TsCallData) HumanTxIEC() string { return formatRate(d.BytesTxF, 1024, iecUnits) }
@@ -0,0 +1,60 @@
+package mproc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTable(t *testing.T) {
+	samples := []TsCallData{
+		{Name: "host", PerInterface: map[string]TsInterfaceRate{
+			"eth0": {BytesRxF: 512, BytesTxF: 100},
+			"eth1": {BytesRxF: 3 * 1024 * 1024, BytesTxF: 1536},
+		}},
+		{Name: "edge", BytesRxF: 2048, BytesTxF: 0},
+	}
+	lines := strings.Split(strings.TrimRight(RenderTable(samples), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("lines = %q, want header and 3 rows", lines)
+	}
+	want := [][]string{
+		{"NAME", "IFACE", "RX", "TX"},
+		{"host", "eth1", "3.0 MiB/s", "1.5 KiB/s"},
+		{"edge", "-", "2.0 KiB/s", "0 B/s"},
+		{"host", "eth0", "512 B/s", "100 B/s"},
+	}
+	for i, line := range lines {
+		got := strings.Fields(line)
+		joined := strings.Join(want[i], " ")
+		if strings.Join(got, " ") != joined {
+			t.Errorf("line %d = %q, want %q", i, line, joined)
+		}
+	}
+}
+
+func TestHumanRate(t *testing.T) {
+	cases := map[float64]string{0: "0 B/s", 1023: "1023 B/s", 1024: "1.0 KiB/s", 1 << 40: "1.0 TiB/s", 1 << 50: "1024.0 TiB/s"}
+	for in, want := range cases {
+		if got := humanRate(in); got != want {
+			t.Errorf("humanRate(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHumanSIAndIEC(t *testing.T) {
+	data := TsCallData{BytesRxF: 12.3e6, BytesTxF: 999}
+	cases := []struct{ got, want string }{
+		{data.HumanRxSI(), "12.3 MB/s"},
+		{data.HumanRxIEC(), "11.7 MiB/s"},
+		{data.HumanTxSI(), "999 B/s"},
+		{data.HumanTxIEC(), "999 B/s"},
+	}
+	for i, c := range cases {
+		if c.got != c.want {
+			t.Errorf("case %d = %q, want %q", i, c.got, c.want)
+		}
+	}
+	if got := (TsCallData{BytesTxF: 1000}).HumanTxSI(); got != "1.0 kB/s" {
+		t.Errorf("HumanTxSI(1000) = %q, want 1.0 kB/s", got)
+	}
+}
@@ -0,0 +1,74 @@
+package mproc
+
+import (
+	"maps"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// TsClassStats 一个流量类 (tc class) 的累计计数
+type TsClassStats struct {
+	Class string // 类标识, 例如 "1:10"
+	Bytes int64
+	Drops int64
+}
+
+// TsClassRate 一个流量类在采样间隔内的速率
+type TsClassRate struct {
+	Bytes      float64 // 每秒字节数
+	Drops      float64 // 每秒丢包数
+	DeltaBytes int64   // 区间内的字节数
+}
+
+// ClassStatsProvider 返回接口上各流量类的累计计数, 例如解析 `tc -s class show dev <iface>` 或查询 netlink;
+// 没有配置 tc 的接口返回空切片
+type ClassStatsProvider func(iface string) ([]TsClassStats, error)
+
+// classTracker 根据相邻两次的类计数计算速率, 只在采样协程中访问
+type classTracker struct {
+	provide ClassStatsProvider
+	prev    map[string]map[string]TsClassStats // 接口 -> 类 -> 上一次的计数
+
+	quietErrors func() bool // 是否不输出错误日志, 见 WithQuietErrors
+}
+
+func newClassTracker(provide ClassStatsProvider) *classTracker {
+	return &classTracker{provide: provide, prev: make(map[string]map[string]TsClassStats)}
+}
+
+// apply 为 PerInterface 中的接口附加各类的速率, 新出现的类只作为基线
+func (c *classTracker) apply(data *TsCallData) {
+	maps.DeleteFunc(c.prev, func(name string, _ map[string]TsClassStats) bool {
+		_, ok := data.PerInterface[name]
+		return !ok
+	})
+	for name, rate := range data.PerInterface {
+		stats, err := c.provide(name)
+		if err != nil {
+			if !c.quietErrors() {
+				mlog.Error(mlog.H{"msg": "failed to read traffic classes", "interface": name, "error": err.Error()})
+			}
+			delete(c.prev, name)
+			continue
+		}
+		cur := make(map[string]TsClassStats, len(stats))
+		for _, s := range stats {
+			cur[s.Class] = s
+			p, ok := c.prev[name][s.Class]
+			if !ok {
+				continue
+			}
+			if rate.Classes == nil {
+				rate.Classes = make(map[string]TsClassRate, len(stats))
+			}
+			delta := counterDelta(p.Bytes, s.Bytes)
+			rate.Classes[s.Class] = TsClassRate{
+				Bytes:      perSecond(delta, data.Interval),
+				Drops:      perSecond(counterDelta(p.Drops, s.Drops), data.Interval),
+				DeltaBytes: delta,
+			}
+		}
+		c.prev[name] = cur
+		data.PerInterface[name] = rate
+	}
+}
@@ -0,0 +1,40 @@
+package mproc
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTrafficClasses(t *testing.T) {
+	var step atomic.Int64
+	provider := func(iface string) ([]TsClassStats, error) {
+		if iface != "eth0" {
+			return nil, errors.New("no qdisc")
+		}
+		n := step.Add(1)
+		return []TsClassStats{
+			{Class: "1:10", Bytes: 100 * n, Drops: n},
+			{Class: "1:20", Bytes: 1000 * n},
+		}, nil
+	}
+	n := newFIFONetDev(t, 50*time.Millisecond, WithTrafficClasses(provider))
+	n.feed(netDevFile("eth0", 0, 0, "lo", 0, 0))
+	n.feed(netDevFile("eth0", 100, 0, "lo", 0, 0))
+	if data := n.next(); data.PerInterface["eth0"].Classes != nil {
+		t.Fatalf("first sample classes = %v, want only a baseline", data.PerInterface["eth0"].Classes)
+	}
+	n.feed(netDevFile("eth0", 200, 0, "lo", 0, 0))
+	data := n.next()
+	classes := data.PerInterface["eth0"].Classes
+	if got := classes["1:10"]; got.DeltaBytes != 100 || got.Bytes != 100/0.05 || got.Drops != 1/0.05 {
+		t.Fatalf("class 1:10 = %+v", got)
+	}
+	if got := classes["1:20"]; got.DeltaBytes != 1000 {
+		t.Fatalf("class 1:20 = %+v", got)
+	}
+	if data.PerInterface["lo"].Classes != nil {
+		t.Fatalf("lo classes = %v, want none when the provider fails", data.PerInterface["lo"].Classes)
+	}
+}
@@ -0,0 +1,112 @@
+package mproc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+// tcpStats /proc/net/snmp 中 Tcp 部分的累计计数
+type tcpStats struct {
+	outSegs, retransSegs int64
+}
+
+// parseSnmpTCP 解析 /proc/net/snmp 的 Tcp 部分: 第一行为字段名, 第二行为对应的值
+func parseSnmpTCP(r io.Reader) (tcpStats, error) {
+	scanner := bufio.NewScanner(r)
+	var header []string
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "Tcp:" {
+			continue
+		}
+		if header == nil {
+			header = fields
+			continue
+		}
+		var s tcpStats
+		var found int
+		for i, name := range header {
+			if i >= len(fields) {
+				break
+			}
+			switch name {
+			case "OutSegs":
+				s.outSegs, found = toCounter(fields[i]), found+1
+			case "RetransSegs":
+				s.retransSegs, found = toCounter(fields[i]), found+1
+			}
+		}
+		if found != 2 {
+			return tcpStats{}, fmt.Errorf("snmp: missing OutSegs or RetransSegs")
+		}
+		return s, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return tcpStats{}, err
+	}
+	return tcpStats{}, fmt.Errorf("snmp: no Tcp section")
+}
+
+// retransRatio 区间内重传的报文段占发送报文段的比例, 没有发送时为 0
+func retransRatio(prev, cur tcpStats) float64 {
+	out := counterDelta(prev.outSegs, cur.outSegs)
+	if out == 0 {
+		return 0
+	}
+	return float64(counterDelta(prev.retransSegs, cur.retransSegs)) / float64(out)
+}
+
+// tcpTracker 每次采样读取 snmp 文件, 计算与上一次读取之间的重传率, 只在采样协程中访问
+type tcpTracker struct {
+	path        string
+	prev        *tcpStats
+	quietErrors func() bool // 是否不输出错误日志, 见 WithQuietErrors
+}
+
+// ratio 读取当前计数并返回与上一次之间的重传率; 第一次读取或读取失败时返回 0
+func (t *tcpTracker) ratio() float64 {
+	f, err := os.Open(t.path)
+	if err != nil {
+		if !t.quietErrors() {
+			mlog.Error(mlog.H{"error": err.Error()})
+		}
+		return 0
+	}
+	defer f.Close()
+	cur, err := parseSnmpTCP(f)
+	if err != nil {
+		if !t.quietErrors() {
+			mlog.Error(mlog.H{"error": err.Error(), "path": t.path})
+		}
+		return 0
+	}
+	prev := t.prev
+	t.prev = &cur
+	if prev == nil {
+		return 0
+	}
+	return retransRatio(*prev, cur)
+}
+
+// WithRetransRatio 每次采样同时读取 /proc/net/snmp (监控进程时为 /proc/<pid>/net/snmp),
+// 在 RetransRatio 中报告区间内 TCP 重传报文段占发送报文段的比例
+func WithRetransRatio(enabled bool) netDevOpts {
+	return func(t *netDev) {
+		t.tcp = nil
+		if enabled {
+			t.tcp = &tcpTracker{}
+		}
+	}
+}
+
+// WithSnmpPath 设置 WithRetransRatio 读取的 snmp 文件, 默认根据 Path 推断
+func WithSnmpPath(path string) netDevOpts {
+	return func(t *netDev) {
+		t.args.SnmpPath = path
+	}
+}
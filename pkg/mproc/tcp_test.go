@@ -0,0 +1,57 @@
+package mproc
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// snmpFile 生成 /proc/net/snmp 的内容, 只有 Tcp 部分的 OutSegs 和 RetransSegs 不为 0
+func snmpFile(outSegs, retransSegs int) string {
+	return "Ip: Forwarding DefaultTTL\nIp: 2 64\n" +
+		"Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors\n" +
+		fmt.Sprintf("Tcp: 1 200 120000 -1 0 0 0 0 0 0 %d %d 0 0 0\n", outSegs, retransSegs) +
+		"Udp: InDatagrams NoPorts\nUdp: 0 0\n"
+}
+
+func TestParseSnmpTCP(t *testing.T) {
+	s, err := parseSnmpTCP(strings.NewReader(snmpFile(1000, 25)))
+	if err != nil || s.outSegs != 1000 || s.retransSegs != 25 {
+		t.Fatalf("parseSnmpTCP = %+v, %v", s, err)
+	}
+	if _, err := parseSnmpTCP(strings.NewReader("Ip: Forwarding\nIp: 1\n")); err == nil {
+		t.Fatal("want an error without a Tcp section")
+	}
+	if r := retransRatio(tcpStats{outSegs: 10}, tcpStats{outSegs: 10, retransSegs: 5}); r != 0 {
+		t.Fatalf("ratio without OutSegs = %v, want 0", r)
+	}
+}
+
+func TestRetransRatio(t *testing.T) {
+	snmp := filepath.Join(t.TempDir(), "snmp")
+	writeFile(t, snmp, snmpFile(1000, 10))
+	n, err := newNetDev("test", time.Second, WithRetransRatio(true), WithSnmpPath(snmp), WithCallback(func(TsCallData) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.tcp.ratio() // 基线, 由采样协程在第一次读取时记录
+
+	writeFile(t, snmp, snmpFile(3000, 60)) // 区间内发送 2000, 重传 50
+	if data := n.sample(snapshot("eth0", 0, 0), snapshot("eth0", 100, 0), sampleTiming{elapsed: time.Second}); data.RetransRatio != 0.025 {
+		t.Fatalf("RetransRatio = %v, want 0.025", data.RetransRatio)
+	}
+	if data := n.sample(snapshot("eth0", 100, 0), snapshot("eth0", 200, 0), sampleTiming{elapsed: time.Second}); data.RetransRatio != 0 {
+		t.Fatalf("RetransRatio = %v, want 0 without OutSegs", data.RetransRatio)
+	}
+
+	// 默认读取与网络设备文件同目录的 snmp
+	pid, err := newNetDev("test", time.Second, WithPath("/proc/42/net/dev"), WithRetransRatio(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid.tcp.path != "/proc/42/net/snmp" {
+		t.Fatalf("snmp path = %q, want /proc/42/net/snmp", pid.tcp.path)
+	}
+}
@@ -0,0 +1,56 @@
+package mproc
+
+import "fmt"
+
+// ifaceThreshold 单个接口的速率阈值, 越过阈值和恢复时各回调一次, 只在采样协程中访问
+type ifaceThreshold struct {
+	iface     string
+	metric    Metric
+	level     int64
+	onExceed  func(iface string, rate TsInterfaceRate)
+	onRecover func(iface string, rate TsInterfaceRate)
+	exceeded  bool
+}
+
+// WithInterfaceThreshold 每次采样检查接口 iface 的 metric (MetricBytesRx 或 MetricBytesTx) 速率,
+// 超过 level 时调用 onExceed, 之后回到 level 以下时调用 onRecover, 持续超过或持续正常时不重复回调.
+// 超过阈值期间接口消失视为恢复, onRecover 的速率为零值. 可以多次使用, 为不同接口或指标设置阈值
+func WithInterfaceThreshold(iface string, metric Metric, level int64, onExceed, onRecover func(iface string, rate TsInterfaceRate)) netDevOpts {
+	return func(t *netDev) {
+		t.thresholds = append(t.thresholds, &ifaceThreshold{
+			iface:     iface,
+			metric:    metric,
+			level:     level,
+			onExceed:  onExceed,
+			onRecover: onRecover,
+		})
+	}
+}
+
+// checkThresholds 检查 WithInterfaceThreshold 的指标是否有每个接口的速率
+func (n *netDev) checkThresholds() error {
+	for _, th := range n.thresholds {
+		if _, ok := th.metric.interfaceRate(TsInterfaceRate{}); !ok {
+			return fmt.Errorf("interface threshold on %s: metric %v has no per-interface rate", th.iface, th.metric)
+		}
+	}
+	return nil
+}
+
+func (th *ifaceThreshold) check(data TsCallData) {
+	rate, ok := data.PerInterface[th.iface]
+	v, _ := th.metric.interfaceRate(rate)
+	over := ok && v > float64(th.level)
+	switch {
+	case over && !th.exceeded:
+		th.exceeded = true
+		if th.onExceed != nil {
+			th.onExceed(th.iface, rate)
+		}
+	case !over && th.exceeded:
+		th.exceeded = false
+		if th.onRecover != nil {
+			th.onRecover(th.iface, rate)
+		}
+	}
+}
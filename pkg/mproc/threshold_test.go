@@ -0,0 +1,41 @@
+package mproc
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithInterfaceThreshold(t *testing.T) {
+	var events []string
+	record := func(kind string) func(string, TsInterfaceRate) {
+		return func(iface string, rate TsInterfaceRate) {
+			events = append(events, fmt.Sprintf("%s %s %d", kind, iface, rate.BytesTx))
+		}
+	}
+	n := &netDev{args: &netDevArgs{Metrics: MetricAll, Callback: func(TsCallData) {}}}
+	WithInterfaceThreshold("eth0", MetricBytesTx, 500, record("exceed"), record("recover"))(n)
+
+	// eth1 的流量不影响 eth0 的阈值
+	counters := []int{0, 100, 700, 1400, 1600, 2400}
+	for i := 1; i < len(counters); i++ {
+		prev := snapshot("eth0", 0, counters[i-1], "eth1", 0, counters[i-1]*10)
+		cur := snapshot("eth0", 0, counters[i], "eth1", 0, counters[i]*10)
+		n.sample(prev, cur, sampleTiming{elapsed: time.Second})
+	}
+	// 超过阈值期间接口消失
+	n.sample(snapshot("eth0", 0, 2400, "eth1", 0, 0), snapshot("eth1", 0, 0), sampleTiming{elapsed: time.Second})
+
+	want := []string{"exceed eth0 600", "recover eth0 200", "exceed eth0 800", "recover eth0 0"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+}
+
+func TestInterfaceThresholdMetric(t *testing.T) {
+	_, err := newNetDev("test", time.Second, WithInterfaceThreshold("eth0", MetricDropRx, 1, nil, nil))
+	if err == nil {
+		t.Fatal("threshold on a metric without per-interface rates: err = nil")
+	}
+}
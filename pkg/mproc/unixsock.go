@@ -0,0 +1,53 @@
+package mproc
+
+import (
+	"net"
+	"time"
+
+	"github.com/lwmacct/250300-go-mod-mlog/pkg/mlog"
+)
+
+const (
+	unixSocketQueueSize    = 1024        // 待发送采样的最大数量, 超出时丢弃新采样
+	unixSocketRedial       = time.Second // 连接失败后重新连接的最短间隔, 其间的采样被丢弃
+	unixSocketWriteTimeout = time.Second // 接收方缓冲区满时单次发送的最长等待
+)
+
+// unixSocketWriter 将每个采样作为一个数据报发送到 unix datagram socket
+type unixSocketWriter struct {
+	*queueSink
+	path   string
+	redial time.Duration
+}
+
+func newUnixSocketWriter(path string) *unixSocketWriter {
+	return (&unixSocketWriter{path: path, redial: unixSocketRedial}).start()
+}
+
+// start 启动发送协程, 之后不能再修改 w 的字段
+func (w *unixSocketWriter) start() *unixSocketWriter {
+	w.queueSink = newQueueSink("unix socket", unixSocketQueueSize, w.run)
+	return w
+}
+
+// WithUnixSocket 将每个采样编码为与 WithProtoWriter 相同的带长度前缀的 protobuf 消息,
+// 作为一个数据报发送到 path 上的 unix datagram socket, 每个数据报可以用 NewProtoReader 读取.
+// 发送在单独的协程中进行, 不会阻塞采样协程; 接收方不存在或发送失败时丢弃采样并定期重新连接
+func WithUnixSocket(path string) netDevOpts {
+	return func(t *netDev) {
+		WithSink(newUnixSocketWriter(path))(t)
+	}
+}
+
+func (w *unixSocketWriter) run(queue <-chan queuedSample) {
+	conn := &redialConn{
+		dial:    func() (net.Conn, error) { return net.Dial("unixgram", w.path) },
+		redial:  w.redial,
+		timeout: unixSocketWriteTimeout,
+		target:  mlog.H{"path": w.path},
+	}
+	defer conn.close()
+	for s := range queue {
+		conn.send(frameSample(s.data))
+	}
+}
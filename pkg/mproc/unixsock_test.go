@@ -0,0 +1,85 @@
+package mproc
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readDatagram 读取一个数据报并解码
+func readDatagram(t *testing.T, l *net.UnixConn) TsCallData {
+	t.Helper()
+	buf := make([]byte, 64<<10)
+	l.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := l.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewProtoReader(bytes.NewReader(buf[:n])).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func listenUnixgram(t *testing.T, path string) *net.UnixConn {
+	t.Helper()
+	l, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mproc.sock")
+	l := listenUnixgram(t, path)
+	n, err := NewNetDev("sock", 10*time.Millisecond,
+		WithStatsSource(NewMockSource(snapshot("eth0", 0, 0), snapshot("eth0", 100, 200))),
+		WithCallback(func(TsCallData) {}),
+		WithUnixSocket(path),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	data := readDatagram(t, l)
+	if data.Name != "sock" || data.DeltaRx != 100 || data.DeltaTx != 200 {
+		t.Fatalf("datagram = %+v, want the first sample", data)
+	}
+}
+
+func TestUnixSocketReconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mproc.sock")
+	w := (&unixSocketWriter{path: path, redial: 10 * time.Millisecond}).start()
+	defer w.Close()
+
+	// 接收方还不存在, 采样被丢弃且不阻塞
+	start := time.Now()
+	w.Write(TsCallData{Name: "lost"})
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("Write blocked without a receiver")
+	}
+	time.Sleep(50 * time.Millisecond) // 等待发送协程连接失败
+
+	l := listenUnixgram(t, path)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				w.Write(TsCallData{Name: "retry"})
+			}
+		}
+	}()
+	if data := readDatagram(t, l); data.Name != "retry" {
+		t.Fatalf("datagram name = %q, want retry after reconnecting", data.Name)
+	}
+}